@@ -1,29 +1,61 @@
 package apierror
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 )
 
 // Base errors.
 var (
-	ErrDuplicateTodo     = New(http.StatusConflict, "this todo already exists")
-	ErrEmptySearchQuery  = New(http.StatusBadRequest, "search query cannot be empty")
-	ErrDBConnection      = New(http.StatusServiceUnavailable, "failed to connect to the database")
-	ErrDBPing            = New(http.StatusServiceUnavailable, "failed to ping database")
-	ErrDBRead            = New(http.StatusInternalServerError, "failed to read from database")
-	ErrMissingDBPassword = New(http.StatusBadRequest, "database password is required")
-	ErrInvalidDBPort     = New(http.StatusBadRequest, "invalid database port number")
-	ErrInvalidRequest    = New(http.StatusBadRequest, "invalid request")
-	ErrInternalServer    = New(http.StatusInternalServerError, "internal server error")
-	ErrNotFound          = New(http.StatusNotFound, "resource not found")
+	ErrDuplicateTodo      = define(http.StatusConflict, "duplicate_todo", "this todo already exists")
+	ErrEmptySearchQuery   = define(http.StatusBadRequest, "empty_search_query", "search query cannot be empty")
+	ErrDBConnection       = define(http.StatusServiceUnavailable, "db_connection", "failed to connect to the database")
+	ErrDBPing             = define(http.StatusServiceUnavailable, "db_ping", "failed to ping database")
+	ErrDBRead             = define(http.StatusInternalServerError, "db_read", "failed to read from database")
+	ErrMissingDBPassword  = define(http.StatusBadRequest, "missing_db_password", "database password is required")
+	ErrInvalidDBPort      = define(http.StatusBadRequest, "invalid_db_port", "invalid database port number")
+	ErrInvalidStorage     = define(http.StatusBadRequest, "invalid_storage", "invalid storage backend")
+	ErrInvalidRequest     = define(http.StatusBadRequest, "invalid_request", "invalid request")
+	ErrInternalServer     = define(http.StatusInternalServerError, "internal_error", "internal server error")
+	ErrNotFound           = define(http.StatusNotFound, "not_found", "resource not found")
+	ErrInvalidStatus      = define(http.StatusBadRequest, "invalid_status", "invalid todo status")
+	ErrInvalidTransition  = define(http.StatusConflict, "invalid_transition", "invalid status transition")
+	ErrInvalidPriority    = define(http.StatusBadRequest, "invalid_priority", "invalid todo priority")
+	ErrUnauthorized       = define(http.StatusUnauthorized, "unauthorized", "authentication required")
+	ErrForbidden          = define(http.StatusForbidden, "forbidden", "insufficient permissions")
+	ErrInvalidRole        = define(http.StatusBadRequest, "invalid_role", "invalid list role")
+	ErrLastOwner          = define(http.StatusConflict, "last_owner", "a list must keep at least one owner")
+	ErrOpenSubtasks       = define(http.StatusConflict, "open_subtasks", "todo has open subtasks")
+	ErrParentDeleted      = define(http.StatusConflict, "parent_deleted", "parent todo is in the trash")
+	ErrPreconditionFailed = define(http.StatusPreconditionFailed, "precondition_failed", "todo has changed since it was read")
+	ErrConcurrentUpdate   = define(http.StatusConflict, "concurrent_update", "todo was changed concurrently, retry the request")
 )
 
+// Kind is a stable, machine-readable error identifier such as "duplicate_todo".
+// Errors with the same kind match each other with errors.Is.
+type Kind string
+
 // APIError represents an API error with HTTP status code.
 type APIError struct {
-	ErrType error  `json:"-"`
-	Inner   error  `json:"-"`
+	Inner error `json:"-"`
+
+	// Kind identifies the error. It is empty for ad hoc errors created with New.
+	Kind    Kind   `json:"error"`
 	Message string `json:"message"`
 	Code    int    `json:"code"`
+
+	// RequestID identifies the request that failed. It is only set on responses.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Details lists per-field validation problems.
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes a validation problem with a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error implements the error interface.
@@ -40,7 +72,26 @@ func (e *APIError) Unwrap() error {
 	return e.Inner
 }
 
-// New creates a new APIError.
+// Is reports whether target is an APIError of the same kind, so that
+// errors.Is(err, ErrDuplicateTodo) holds however often err has been wrapped.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok || t.Kind == "" {
+		return false
+	}
+
+	return e.Kind == t.Kind
+}
+
+// WithDetails returns a copy of the error carrying field validation details.
+func (e *APIError) WithDetails(details ...FieldError) *APIError {
+	withDetails := *e
+	withDetails.Details = append(append([]FieldError(nil), e.Details...), details...)
+
+	return &withDetails
+}
+
+// New creates a new APIError without a kind.
 func New(code int, message string) *APIError {
 	return &APIError{
 		Code:    code,
@@ -49,10 +100,98 @@ func New(code int, message string) *APIError {
 }
 
 // Wrap wraps an error with additional context.
+// The kind and details of a wrapped APIError are kept.
 func Wrap(err error, code int, message string) *APIError {
-	return &APIError{
+	wrapped := &APIError{
 		Code:    code,
 		Message: message,
 		Inner:   err,
 	}
+
+	var inner *APIError
+	if errors.As(err, &inner) {
+		wrapped.Kind = inner.Kind
+		wrapped.Details = inner.Details
+	}
+
+	return wrapped
+}
+
+// Response returns the APIError to send to a client for err, tagged with requestID.
+//
+// The status comes from the innermost APIError with a kind, so a 409 wrapped as a 500
+// further up is still reported as a 409, together with the outermost message for that
+// status. Errors that are not APIErrors become ErrInternalServer so internal details are
+// not leaked.
+func Response(err error, requestID string) *APIError {
+	chain := apiErrors(err)
+	if len(chain) == 0 {
+		resp := *ErrInternalServer
+		resp.RequestID = requestID
+
+		return &resp
+	}
+
+	origin := chain[0]
+	for _, e := range chain {
+		if e.Kind != "" {
+			origin = e
+		}
+	}
+
+	resp := &APIError{
+		Kind:      origin.Kind,
+		Message:   origin.Message,
+		Code:      origin.Code,
+		RequestID: requestID,
+		Details:   origin.Details,
+	}
+
+	for _, e := range chain {
+		if e.Code == origin.Code {
+			resp.Message = e.Message
+			resp.Details = e.Details
+
+			break
+		}
+	}
+
+	if resp.Kind == "" {
+		resp.Kind = statusKind(resp.Code)
+	}
+
+	return resp
+}
+
+// apiErrors returns the APIErrors in the chain of err, outermost first.
+func apiErrors(err error) []*APIError {
+	var chain []*APIError
+	for {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			return chain
+		}
+
+		chain = append(chain, apiErr)
+		err = apiErr.Inner
+	}
+}
+
+// define creates a base error of the given kind.
+func define(code int, kind Kind, message string) *APIError {
+	return &APIError{
+		Code:    code,
+		Message: message,
+		Kind:    kind,
+	}
+}
+
+// statusKind derives a kind from an HTTP status, for example "not_found" from 404.
+func statusKind(code int) Kind {
+	text := http.StatusText(code)
+	if text == "" {
+		return "error"
+	}
+
+	return Kind(strings.ReplaceAll(strings.ToLower(text), " ", "_"))
 }
@@ -2,6 +2,7 @@ package apierror_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -135,4 +136,186 @@ func TestWrap(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+func TestKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *apierror.APIError
+		want apierror.Kind
+	}{
+		{
+			name: "base error",
+			err:  apierror.ErrDuplicateTodo,
+			want: "duplicate_todo",
+		},
+		{
+			name: "new error has no kind",
+			err:  apierror.New(http.StatusNotFound, "test error"),
+			want: "",
+		},
+		{
+			name: "wrapped base error keeps its kind",
+			err:  apierror.Wrap(apierror.ErrInvalidStatus, http.StatusBadRequest, "test error"),
+			want: "invalid_status",
+		},
+		{
+			name: "doubly wrapped base error keeps its kind",
+			err: apierror.Wrap(
+				fmt.Errorf("context: %w", apierror.Wrap(apierror.ErrDuplicateTodo, http.StatusConflict, "test error")),
+				http.StatusInternalServerError,
+				"outer error",
+			),
+			want: "duplicate_todo",
+		},
+		{
+			name: "wrapped plain error has no kind",
+			err:  apierror.Wrap(errors.New("inner error"), http.StatusServiceUnavailable, "test error"),
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Kind != tt.want {
+				t.Errorf("Kind = %v, want %v", tt.err.Kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	duplicate := apierror.Wrap(apierror.ErrDuplicateTodo, http.StatusConflict, "todo exists")
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{
+			name:   "same base error",
+			err:    apierror.ErrNotFound,
+			target: apierror.ErrNotFound,
+			want:   true,
+		},
+		{
+			name:   "wrapped by another APIError",
+			err:    apierror.Wrap(duplicate, http.StatusInternalServerError, "failed to add todo"),
+			target: apierror.ErrDuplicateTodo,
+			want:   true,
+		},
+		{
+			name:   "wrapped by fmt.Errorf",
+			err:    fmt.Errorf("add: %w", duplicate),
+			target: apierror.ErrDuplicateTodo,
+			want:   true,
+		},
+		{
+			name:   "copy of a base error",
+			err:    apierror.ErrInvalidRequest.WithDetails(apierror.FieldError{Field: "item", Message: "required"}),
+			target: apierror.ErrInvalidRequest,
+			want:   true,
+		},
+		{
+			name:   "different kind",
+			err:    duplicate,
+			target: apierror.ErrNotFound,
+			want:   false,
+		},
+		{
+			name:   "errors without a kind",
+			err:    apierror.New(http.StatusConflict, "test error"),
+			target: apierror.New(http.StatusConflict, "test error"),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIError_WithDetails(t *testing.T) {
+	detail := apierror.FieldError{Field: "item", Message: "must not be empty"}
+
+	got := apierror.ErrInvalidRequest.WithDetails(detail)
+	if len(got.Details) != 1 || got.Details[0] != detail {
+		t.Errorf("WithDetails() details = %v, want [%v]", got.Details, detail)
+	}
+
+	if len(apierror.ErrInvalidRequest.Details) != 0 {
+		t.Errorf("WithDetails() modified the base error: %v", apierror.ErrInvalidRequest.Details)
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    int
+		wantKind    apierror.Kind
+		wantMessage string
+	}{
+		{
+			name:        "api error",
+			err:         apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found"),
+			wantCode:    http.StatusNotFound,
+			wantKind:    "not_found",
+			wantMessage: "todo item not found",
+		},
+		{
+			name: "status of innermost kind",
+			err: apierror.Wrap(
+				apierror.Wrap(apierror.ErrDuplicateTodo, http.StatusConflict, "todo already exists"),
+				http.StatusInternalServerError,
+				"failed to add todo",
+			),
+			wantCode:    http.StatusConflict,
+			wantKind:    "duplicate_todo",
+			wantMessage: "todo already exists",
+		},
+		{
+			name:        "error without kind",
+			err:         apierror.Wrap(errors.New("timeout"), http.StatusServiceUnavailable, "database unavailable"),
+			wantCode:    http.StatusServiceUnavailable,
+			wantKind:    "service_unavailable",
+			wantMessage: "database unavailable",
+		},
+		{
+			name:        "plain error",
+			err:         errors.New("connection reset"),
+			wantCode:    http.StatusInternalServerError,
+			wantKind:    "internal_error",
+			wantMessage: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := apierror.Response(tt.err, "req-1")
+			if got.Code != tt.wantCode {
+				t.Errorf("Response() code = %v, want %v", got.Code, tt.wantCode)
+			}
+			if got.Kind != tt.wantKind {
+				t.Errorf("Response() kind = %v, want %v", got.Kind, tt.wantKind)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("Response() message = %v, want %v", got.Message, tt.wantMessage)
+			}
+			if got.RequestID != "req-1" {
+				t.Errorf("Response() request ID = %v, want %v", got.RequestID, "req-1")
+			}
+			if got.Inner != nil {
+				t.Errorf("Response() inner = %v, want nil", got.Inner)
+			}
+		})
+	}
+
+	if apierror.ErrInternalServer.RequestID != "" {
+		t.Error("Response() modified the base error")
+	}
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyHeader is the header that carries a static API key.
+const APIKeyHeader = "X-API-Key"
+
+// Authentication methods of a Principal.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+
+	// MethodHeader identifies callers by a header set by a trusted proxy, without verifying them.
+	MethodHeader = "header"
+)
+
+// Scopes grant access to the API. Bearer tokens carry them in the space separated
+// scope claim; API keys are granted ScopeRead and ScopeWrite.
+const (
+	ScopeRead  = "todo:read"
+	ScopeWrite = "todo:write"
+
+	// ScopeAdmin grants access to the todos of every owner.
+	ScopeAdmin = "todo:admin"
+)
+
+// leeway tolerates clock skew when checking the time claims of bearer tokens.
+const leeway = 30 * time.Second
+
+// Errors returned by Authenticate. Each is a 401 apierror.ErrUnauthorized.
+var (
+	ErrMissingCredentials = apierror.Wrap(apierror.ErrUnauthorized, http.StatusUnauthorized,
+		"missing API key or bearer token")
+	ErrInvalidAPIKey = apierror.Wrap(apierror.ErrUnauthorized, http.StatusUnauthorized, "invalid API key")
+	ErrInvalidToken  = apierror.Wrap(apierror.ErrUnauthorized, http.StatusUnauthorized, "invalid bearer token")
+)
+
+// Principal is an authenticated client.
+type Principal struct {
+	// Subject identifies the client: the API key name or the sub claim of the token, prefixed
+	// with the method and a colon so that a key and a token of the same name stay apart, or the
+	// identity header. It is the owner of the client's todos.
+	Subject string
+
+	// Method is MethodAPIKey, MethodJWT or MethodHeader.
+	Method string
+
+	Scopes []string
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// principalKey is the context key of the Principal.
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+
+	return principal, ok
+}
+
+// claims are the bearer token claims the API reads.
+type claims struct {
+	jwt.RegisteredClaims
+
+	Scope string `json:"scope"`
+}
+
+// apiKey is a configured API key.
+type apiKey struct {
+	name string
+	hash []byte
+}
+
+// Authenticator verifies API keys and bearer tokens.
+type Authenticator struct {
+	apiKeys []apiKey
+	secret  []byte
+	keys    map[string]crypto.PublicKey
+	parser  *jwt.Parser
+}
+
+// New creates an Authenticator for the configured credentials, reading the JWKS file if one is set.
+func New(cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		secret: []byte(cfg.JWTSecret),
+	}
+
+	for _, key := range cfg.APIKeys {
+		a.apiKeys = append(a.apiKeys, apiKey{name: key.Name, hash: key.Hash})
+	}
+
+	var methods []string
+	if cfg.JWTSecret != "" {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		a.keys = keys
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+
+		jwt.WithExpirationRequired(),
+
+		jwt.WithLeeway(leeway),
+	}
+
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Authenticate returns the principal for the credentials of req. An API key in the
+// X-API-Key header takes precedence over a bearer token in the Authorization header.
+func (a *Authenticator) Authenticate(req *http.Request) (Principal, error) {
+	if key := req.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	scheme, token, found := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	return a.authenticateToken(strings.TrimSpace(token))
+}
+
+// authenticateAPIKey matches key against the configured key hashes.
+func (a *Authenticator) authenticateAPIKey(key string) (Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+
+	for _, candidate := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], candidate.hash) == 1 {
+			return Principal{
+				Subject: subject(MethodAPIKey, candidate.name),
+
+				Method: MethodAPIKey,
+
+				Scopes: []string{ScopeRead, ScopeWrite},
+			}, nil
+		}
+	}
+
+	return Principal{}, ErrInvalidAPIKey
+}
+
+// authenticateToken verifies a bearer token and reads the principal from its claims.
+func (a *Authenticator) authenticateToken(raw string) (Principal, error) {
+	var tokenClaims claims
+	if _, err := a.parser.ParseWithClaims(raw, &tokenClaims, a.key); err != nil {
+		return Principal{}, apierror.Wrap(fmt.Errorf("%w: %w", ErrInvalidToken, err),
+			http.StatusUnauthorized, ErrInvalidToken.Message)
+	}
+
+	if tokenClaims.Subject == "" {
+		return Principal{}, apierror.Wrap(ErrInvalidToken, http.StatusUnauthorized, "bearer token has no subject")
+	}
+
+	return Principal{
+		Subject: subject(MethodJWT, tokenClaims.Subject),
+
+		Method: MethodJWT,
+
+		Scopes: strings.Fields(tokenClaims.Scope),
+	}, nil
+}
+
+// subject returns the Subject of a principal authenticated by method with the name id.
+func subject(method, id string) string {
+	return method + ":" + id
+}
+
+// key returns the key that verifies token: the HMAC secret, or the JWKS key named by
+// the kid header. A token without kid is accepted if the set holds a single key.
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,302 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret   = "test-secret"
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "golandworks-api"
+)
+
+// encodeInt encodes an integer as a base64url JWK value.
+func encodeInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// writeJWKS writes a key set with the public halves of the keys to a temporary file.
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	set := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"n":   encodeInt(rsaKey.N),
+				"e":   encodeInt(big.NewInt(int64(rsaKey.E))),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   encodeInt(ecKey.X),
+				"y":   encodeInt(ecKey.Y),
+			},
+			{
+				"kty": "RSA",
+				"kid": "enc-1",
+				"use": "enc",
+				"n":   encodeInt(rsaKey.N),
+				"e":   encodeInt(big.NewInt(int64(rsaKey.E))),
+			},
+		},
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	return path
+}
+
+// sign signs a token with the given claims, setting kid if it is not empty.
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signed
+}
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	keyHash := sha256.Sum256([]byte("ci-key"))
+
+	authenticator, err := auth.New(config.AuthConfig{
+		APIKeys: []config.APIKey{{Name: "ci", Hash: keyHash[:]}},
+
+		JWTSecret: testSecret,
+
+		JWKSFile: writeJWKS(t, rsaKey, ecKey),
+
+		JWTIssuer: testIssuer,
+
+		JWTAudience: testAudience,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   testIssuer,
+			"aud":   testAudience,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "todo:read todo:write",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		apiKey      string
+		bearer      string
+		wantSubject string
+		wantMethod  string
+		wantScopes  []string
+		wantErr     string
+	}{
+		{
+			name:        "API key",
+			apiKey:      "ci-key",
+			wantSubject: "api_key:ci",
+			wantMethod:  auth.MethodAPIKey,
+			wantScopes:  []string{auth.ScopeRead, auth.ScopeWrite},
+		},
+		{
+			name:    "wrong API key",
+			apiKey:  "other-key",
+			wantErr: "invalid API key",
+		},
+		{
+			name:    "no credentials",
+			wantErr: "missing API key or bearer token",
+		},
+		{
+			name:        "HMAC token",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(nil)),
+			wantSubject: "jwt:user-1",
+			wantMethod:  auth.MethodJWT,
+			wantScopes:  []string{auth.ScopeRead, auth.ScopeWrite},
+		},
+		{
+			name:        "token named like an API key",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"sub": "ci"})),
+			wantSubject: "jwt:ci",
+			wantMethod:  auth.MethodJWT,
+			wantScopes:  []string{auth.ScopeRead, auth.ScopeWrite},
+		},
+		{
+			name:        "RSA token from JWKS",
+			bearer:      sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"scope": "todo:read"})),
+			wantSubject: "jwt:user-1",
+			wantMethod:  auth.MethodJWT,
+			wantScopes:  []string{auth.ScopeRead},
+		},
+		{
+			name:        "ECDSA token from JWKS",
+			bearer:      sign(t, jwt.SigningMethodES256, ecKey, "ec-1", claims(nil)),
+			wantSubject: "jwt:user-1",
+			wantMethod:  auth.MethodJWT,
+			wantScopes:  []string{auth.ScopeRead, auth.ScopeWrite},
+		},
+		{
+			name:    "wrong secret",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims(nil)),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "unknown signing key",
+			bearer:  sign(t, jwt.SigningMethodRS256, otherKey, "rsa-1", claims(nil)),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "encryption key",
+			bearer:  sign(t, jwt.SigningMethodRS256, rsaKey, "enc-1", claims(nil)),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "unsigned token",
+			bearer:  sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil)),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "expired",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "no expiry",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": nil})),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "wrong issuer",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "wrong audience",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"aud": "other-api"})),
+			wantErr: "invalid bearer token",
+		},
+		{
+			name:    "no subject",
+			bearer:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"sub": nil})),
+			wantErr: "bearer token has no subject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/todo", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.apiKey)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+
+			principal, err := authenticator.Authenticate(req)
+			if tt.wantErr != "" {
+				if !errors.Is(err, apierror.ErrUnauthorized) {
+					t.Fatalf("Authenticate() error = %v, want unauthorized", err)
+				}
+
+				if got := apierror.Response(err, "").Message; got != tt.wantErr {
+					t.Errorf("Authenticate() message = %q, want %q", got, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+
+			if principal.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", principal.Subject, tt.wantSubject)
+			}
+			if principal.Method != tt.wantMethod {
+				t.Errorf("Method = %q, want %q", principal.Method, tt.wantMethod)
+			}
+			if !slices.Equal(principal.Scopes, tt.wantScopes) {
+				t.Errorf("Scopes = %v, want %v", principal.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := auth.FromContext(context.Background()); ok {
+		t.Error("FromContext() found a principal in an empty context")
+	}
+
+	want := auth.Principal{Subject: "user-1", Method: auth.MethodJWT, Scopes: []string{auth.ScopeRead}}
+
+	got, ok := auth.FromContext(auth.NewContext(context.Background(), want))
+	if !ok || got.Subject != want.Subject || !got.HasScope(auth.ScopeRead) || got.HasScope(auth.ScopeWrite) {
+		t.Errorf("FromContext() = %+v, %v, want %+v", got, ok, want)
+	}
+}
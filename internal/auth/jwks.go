@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwk is a JSON Web Key as defined in RFC 7517. Only the fields of public RSA and EC keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+
+	// RSA modulus and exponent.
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC curve and coordinates.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS reads the public signing keys of a JSON Web Key Set file, keyed by key ID.
+// Keys of other types and encryption keys are ignored.
+func LoadJWKS(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", path, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var (
+			publicKey crypto.PublicKey
+			keyErr    error
+		)
+
+		switch key.Kty {
+		case "RSA":
+			publicKey, keyErr = key.rsa()
+		case "EC":
+			publicKey, keyErr = key.ecdsa()
+		default:
+			continue
+		}
+
+		if keyErr != nil {
+			return nil, fmt.Errorf("invalid key %d in JWKS file %s: %w", i, path, keyErr)
+		}
+
+		keys[key.Kid] = publicKey
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no RSA or EC signing keys", path)
+	}
+
+	return keys, nil
+}
+
+// rsa returns the RSA public key.
+func (k jwk) rsa() (*rsa.PublicKey, error) {
+	n, err := decodeInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+
+	e, err := decodeInt(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent out of range")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// ecdsa returns the ECDSA public key, checking that the point is on the curve.
+func (k jwk) ecdsa() (*ecdsa.PublicKey, error) {
+	var (
+		curve elliptic.Curve
+		check ecdh.Curve
+	)
+
+	switch k.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeInt(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("x coordinate: %w", err)
+	}
+
+	y, err := decodeInt(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y coordinate: %w", err)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if x.BitLen() > size*8 || y.BitLen() > size*8 {
+		return nil, errors.New("coordinate too large")
+	}
+
+	// Uncompressed point encoding: 0x04 || x || y.
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
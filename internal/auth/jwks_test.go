@@ -0,0 +1,79 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/auth"
+)
+
+func TestLoadJWKS(t *testing.T) {
+	// A valid P-256 public key from RFC 7517, appendix A.1.
+	const (
+		x = "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4"
+		y = "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"
+	)
+
+	tests := []struct {
+		name     string
+		content  string
+		wantKeys int
+		wantErr  bool
+	}{
+		{
+			name:     "EC key",
+			content:  `{"keys":[{"kty":"EC","kid":"1","crv":"P-256","x":"` + x + `","y":"` + y + `"}]}`,
+			wantKeys: 1,
+		},
+		{
+			name:    "point not on curve",
+			content: `{"keys":[{"kty":"EC","kid":"1","crv":"P-256","x":"` + x + `","y":"` + x + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported curve",
+			content: `{"keys":[{"kty":"EC","kid":"1","crv":"P-192","x":"` + x + `","y":"` + y + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "RSA key without exponent",
+			content: `{"keys":[{"kty":"RSA","kid":"1","n":"` + x + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "only unsupported keys",
+			content: `{"keys":[{"kty":"oct","kid":"1","k":"c2VjcmV0"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"keys":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write JWKS: %v", err)
+			}
+
+			keys, err := auth.LoadJWKS(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadJWKS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(keys) != tt.wantKeys {
+				t.Errorf("LoadJWKS() returned %d keys, want %d", len(keys), tt.wantKeys)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := auth.LoadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("LoadJWKS() error = nil, want error")
+		}
+	})
+}
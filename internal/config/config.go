@@ -1,10 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/brkcnr/golandworks-api/internal/apierror"
 	"github.com/joho/godotenv"
@@ -21,11 +29,165 @@ type DBConfig struct {
 	Host string
 
 	Port int
+
+	// AutoMigrate applies pending schema migrations when the server starts.
+	AutoMigrate bool
+}
+
+// HTTPConfig is the HTTP server configuration.
+type HTTPConfig struct {
+	// Addr is the TCP address to listen on, for example ":8080".
+	Addr string
+
+	ReadTimeout time.Duration
+
+	WriteTimeout time.Duration
+
+	IdleTimeout time.Duration
+
+	ReadHeaderTimeout time.Duration
+
+	// ShutdownTimeout is how long in-flight requests may take to finish after a shutdown signal.
+	ShutdownTimeout time.Duration
+
+	// DrainDelay is how long the server keeps serving with readiness failing after a shutdown
+	// signal, so load balancers stop routing to it before it stops accepting connections.
+	DrainDelay time.Duration
+
+	// ReadinessTimeout bounds each dependency check of the readiness endpoint.
+	ReadinessTimeout time.Duration
+
+	// TrustRequestID keeps a valid X-Request-ID sent by the client instead of generating a new one.
+	TrustRequestID bool
+
+	// AccessLog logs every completed request.
+	AccessLog bool
+
+	CORS CORSConfig
+}
+
+// CORSConfig is the cross-origin resource sharing configuration.
+// CORS is disabled when AllowedOrigins is empty.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins that may call the API, or "*" for any origin.
+	AllowedOrigins []string
+
+	AllowedMethods []string
+
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers browsers may read.
+	ExposedHeaders []string
+
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
 }
 
+// LogConfig is the logging configuration.
+type LogConfig struct {
+	// Level is the minimum level that is logged.
+	Level slog.Level
+
+	// Format is either LogFormatJSON or LogFormatText.
+	Format string
+}
+
+// Log output formats.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// TraceConfig is the tracing configuration.
+type TraceConfig struct {
+	// Exporter is TraceExporterNone, TraceExporterStdout or TraceExporterOTLP.
+	// The OTLP exporter reads its endpoint from the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+
+	ServiceName string
+
+	// SampleRatio is the share of new traces that are recorded, between 0 and 1.
+	// Traces started by a caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Trace exporters.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOTLP   = "otlp"
+)
+
+// AuthConfig is the authentication configuration. Authentication is enabled when any
+// API key, JWT secret or JWKS file is configured.
+type AuthConfig struct {
+	APIKeys []APIKey
+
+	// JWTSecret verifies HMAC signed bearer tokens.
+	JWTSecret string
+
+	// JWKSFile is the path of a JSON Web Key Set that verifies RSA and ECDSA signed bearer tokens.
+	JWKSFile string
+
+	// JWTIssuer and JWTAudience, if set, must match the iss and aud claims of bearer tokens.
+	JWTIssuer   string
+	JWTAudience string
+
+	// IdentityHeader names a header that identifies callers without authenticating them, for
+	// deployments behind a proxy that authenticates and sets it. It cannot be combined with credentials.
+	IdentityHeader string
+}
+
+// Enabled reports whether any credentials are configured.
+func (c AuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWTSecret != "" || c.JWKSFile != ""
+}
+
+// APIKey is a static API key. Only the hash of the key is configured, never the key itself.
+type APIKey struct {
+	// Name identifies the client using the key.
+	Name string
+
+	// Hash is the SHA-256 hash of the key.
+	Hash []byte
+}
+
+// TodoConfig is the configuration of todo rules.
+type TodoConfig struct {
+	// StrictSubtasks keeps todos from being marked DONE while they have open subtasks.
+	StrictSubtasks bool
+
+	// TrashRetention is how long deleted todos stay in the trash; zero keeps them forever.
+	TrashRetention time.Duration
+
+	// TrashPurgeInterval is how often todos past the retention period are purged from the trash.
+	TrashPurgeInterval time.Duration
+}
+
+// Storage backends.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 // Config is the application configuration.
 type Config struct {
+	// Storage selects the Storer implementation, either StoragePostgres or StorageMemory.
+	Storage string
+
 	DB DBConfig
+
+	HTTP HTTPConfig
+
+	Log LogConfig
+
+	Trace TraceConfig
+
+	Auth AuthConfig
+
+	Todo TodoConfig
 }
 
 // ConnectionString returns the full database connection string.
@@ -64,17 +226,209 @@ func (c DBConfig) SafeConnectionString() string {
 
 // Load loads the configuration from environment variables.
 func Load() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
+	// The .env file is optional so the server can run from environment variables alone.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	dbConfig, err := loadDBConfig()
+	httpConfig, err := loadHTTPConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logConfig, err := loadLogConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Config{
-		DB: *dbConfig,
+	traceConfig, err := loadTraceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig, err := loadAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	todoConfig, err := loadTodoConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Storage: GetEnvOrDefault("STORAGE", StoragePostgres),
+
+		HTTP: *httpConfig,
+
+		Log: *logConfig,
+
+		Trace: *traceConfig,
+
+		Auth: *authConfig,
+
+		Todo: *todoConfig,
+	}
+
+	switch cfg.Storage {
+	case StorageMemory:
+		// The in-memory store needs no database settings.
+	case StoragePostgres:
+		dbConfig, dbErr := loadDBConfig()
+		if dbErr != nil {
+			return nil, dbErr
+		}
+
+		cfg.DB = *dbConfig
+	default:
+		return nil, apierror.Wrap(
+
+			apierror.ErrInvalidStorage,
+
+			http.StatusBadRequest,
+
+			fmt.Sprintf("unknown STORAGE %q, want %q or %q", cfg.Storage, StoragePostgres, StorageMemory),
+		)
+	}
+
+	return cfg, nil
+}
+
+// loadHTTPConfig loads the HTTP server configuration from environment variables.
+func loadHTTPConfig() (*HTTPConfig, error) {
+	config := &HTTPConfig{
+		Addr: GetEnvOrDefault("HTTP_ADDR", ":8080"),
+	}
+
+	durations := []struct {
+		key          string
+		defaultValue string
+		target       *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", "15s", &config.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", "15s", &config.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "60s", &config.IdleTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", "5s", &config.ReadHeaderTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", "30s", &config.ShutdownTimeout},
+		{"HTTP_DRAIN_DELAY", "5s", &config.DrainDelay},
+		{"HTTP_READINESS_TIMEOUT", "2s", &config.ReadinessTimeout},
+	}
+
+	for _, d := range durations {
+		value, err := time.ParseDuration(GetEnvOrDefault(d.key, d.defaultValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.key, err)
+		}
+
+		if value < 0 {
+			return nil, fmt.Errorf("invalid %s: duration cannot be negative", d.key)
+		}
+
+		*d.target = value
+	}
+
+	bools := []struct {
+		key          string
+		defaultValue string
+		target       *bool
+	}{
+		{"HTTP_TRUST_REQUEST_ID", "true", &config.TrustRequestID},
+		{"HTTP_ACCESS_LOG", "true", &config.AccessLog},
+		{"CORS_ALLOW_CREDENTIALS", "false", &config.CORS.AllowCredentials},
+	}
+
+	for _, b := range bools {
+		value, err := strconv.ParseBool(GetEnvOrDefault(b.key, b.defaultValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", b.key, err)
+		}
+
+		*b.target = value
+	}
+
+	maxAge, err := time.ParseDuration(GetEnvOrDefault("CORS_MAX_AGE", "10m"))
+	if err != nil || maxAge < 0 {
+		return nil, fmt.Errorf("invalid CORS_MAX_AGE %q", os.Getenv("CORS_MAX_AGE"))
+	}
+
+	config.CORS.AllowedOrigins = listEnv("CORS_ALLOWED_ORIGINS", "")
+	config.CORS.AllowedMethods = listEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE")
+	config.CORS.AllowedHeaders = listEnv("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-API-Key,X-Request-ID,If-Match,If-None-Match")
+	config.CORS.ExposedHeaders = listEnv("CORS_EXPOSED_HEADERS", "X-Request-ID,ETag")
+	config.CORS.MaxAge = maxAge
+
+	if config.CORS.AllowCredentials && slices.Contains(config.CORS.AllowedOrigins, "*") {
+		return nil, errors.New("invalid CORS_ALLOWED_ORIGINS: \"*\" cannot be combined with CORS_ALLOW_CREDENTIALS")
+	}
+
+	return config, nil
+}
+
+// loadLogConfig loads the logging configuration from environment variables.
+func loadLogConfig() (*LogConfig, error) {
+	config := &LogConfig{
+		Format: GetEnvOrDefault("LOG_FORMAT", LogFormatJSON),
+	}
+
+	if err := config.Level.UnmarshalText([]byte(GetEnvOrDefault("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+
+	if config.Format != LogFormatJSON && config.Format != LogFormatText {
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q, want %q or %q", config.Format, LogFormatJSON, LogFormatText)
+	}
+
+	return config, nil
+}
+
+// loadTraceConfig loads the tracing configuration from environment variables.
+func loadTraceConfig() (*TraceConfig, error) {
+	config := &TraceConfig{
+		Exporter: GetEnvOrDefault("TRACE_EXPORTER", TraceExporterNone),
+
+		ServiceName: GetEnvOrDefault("OTEL_SERVICE_NAME", "golandworks-api"),
+	}
+
+	switch config.Exporter {
+	case TraceExporterNone, TraceExporterStdout, TraceExporterOTLP:
+	default:
+		return nil, fmt.Errorf("invalid TRACE_EXPORTER %q, want %q, %q or %q",
+			config.Exporter, TraceExporterNone, TraceExporterStdout, TraceExporterOTLP)
+	}
+
+	ratio, err := strconv.ParseFloat(GetEnvOrDefault("TRACE_SAMPLE_RATIO", "1"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("invalid TRACE_SAMPLE_RATIO %q, want a number between 0 and 1",
+			os.Getenv("TRACE_SAMPLE_RATIO"))
+	}
+	config.SampleRatio = ratio
+
+	return config, nil
+}
+
+// loadTodoConfig loads the todo rules from environment variables.
+func loadTodoConfig() (*TodoConfig, error) {
+	strict, err := strconv.ParseBool(GetEnvOrDefault("TODO_STRICT_SUBTASKS", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TODO_STRICT_SUBTASKS: %w", err)
+	}
+
+	retention, err := time.ParseDuration(GetEnvOrDefault("TODO_TRASH_RETENTION", "720h"))
+	if err != nil || retention < 0 {
+		return nil, fmt.Errorf("invalid TODO_TRASH_RETENTION %q", os.Getenv("TODO_TRASH_RETENTION"))
+	}
+
+	interval, err := time.ParseDuration(GetEnvOrDefault("TODO_TRASH_PURGE_INTERVAL", "1h"))
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid TODO_TRASH_PURGE_INTERVAL %q", os.Getenv("TODO_TRASH_PURGE_INTERVAL"))
+	}
+
+	return &TodoConfig{
+		StrictSubtasks: strict,
+
+		TrashRetention: retention,
+
+		TrashPurgeInterval: interval,
 	}, nil
 }
 
@@ -85,6 +439,11 @@ func loadDBConfig() (*DBConfig, error) {
 	if portErr != nil {
 		return nil, fmt.Errorf("invalid DB_PORT: %w", portErr)
 	}
+
+	autoMigrate, migrateErr := strconv.ParseBool(GetEnvOrDefault("DB_AUTO_MIGRATE", "true"))
+	if migrateErr != nil {
+		return nil, fmt.Errorf("invalid DB_AUTO_MIGRATE: %w", migrateErr)
+	}
 	config := &DBConfig{
 		User: GetEnvOrDefault("DB_USER", "postgres"),
 
@@ -95,6 +454,8 @@ func loadDBConfig() (*DBConfig, error) {
 		Host: GetEnvOrDefault("DB_HOST", "localhost"),
 
 		Port: port,
+
+		AutoMigrate: autoMigrate,
 	}
 
 	if err := config.Validate(); err != nil {
@@ -131,6 +492,51 @@ func (c DBConfig) Validate() error {
 	return nil
 }
 
+// loadAuthConfig loads the authentication configuration from environment variables.
+func loadAuthConfig() (*AuthConfig, error) {
+	config := &AuthConfig{
+		JWTSecret: os.Getenv("AUTH_JWT_SECRET"),
+
+		JWKSFile: os.Getenv("AUTH_JWKS_FILE"),
+
+		JWTIssuer: os.Getenv("AUTH_JWT_ISSUER"),
+
+		JWTAudience: os.Getenv("AUTH_JWT_AUDIENCE"),
+
+		IdentityHeader: os.Getenv("AUTH_IDENTITY_HEADER"),
+	}
+
+	// Each key is written as name:sha256hex, for example ci:9f86d08188...
+	for i, entry := range listEnv("AUTH_API_KEYS", "") {
+		name, hexHash, found := strings.Cut(entry, ":")
+		hash, err := hex.DecodeString(hexHash)
+		if !found || name == "" || err != nil || len(hash) != sha256.Size {
+			// The entry itself is not echoed, in case it holds a raw key.
+			return nil, fmt.Errorf("invalid AUTH_API_KEYS entry %d, want name:<hex SHA-256 of the key>", i+1)
+		}
+
+		config.APIKeys = append(config.APIKeys, APIKey{Name: name, Hash: hash})
+	}
+
+	if config.IdentityHeader != "" && config.Enabled() {
+		return nil, errors.New("AUTH_IDENTITY_HEADER cannot be combined with API keys or JWT keys")
+	}
+
+	return config, nil
+}
+
+// listEnv splits a comma-separated environment variable, dropping empty entries.
+func listEnv(key, defaultValue string) []string {
+	var list []string
+	for _, value := range strings.Split(GetEnvOrDefault(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
+
 // GetEnvOrDefault gets the environment variable or the default value.
 func GetEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
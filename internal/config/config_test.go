@@ -1,8 +1,11 @@
 package config_test
 
 import (
+	"log/slog"
 	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/brkcnr/golandworks-api/internal/config"
 )
@@ -138,6 +141,394 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoad_Storage(t *testing.T) {
+	tests := []struct {
+		name        string
+		storage     string
+		wantStorage string
+		wantErr     bool
+	}{
+		{
+			name:        "memory storage needs no database password",
+			storage:     "memory",
+			wantStorage: config.StorageMemory,
+		},
+		{
+			name:    "postgres storage requires a database password",
+			storage: "postgres",
+			wantErr: true,
+		},
+		{
+			name:    "unknown storage",
+			storage: "sqlite",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORAGE", tt.storage)
+			t.Setenv("DB_PASSWORD", "")
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && cfg.Storage != tt.wantStorage {
+				t.Errorf("Load() Storage = %v, want %v", cfg.Storage, tt.wantStorage)
+			}
+		})
+	}
+}
+
+func TestLoad_HTTP(t *testing.T) {
+	t.Setenv("STORAGE", config.StorageMemory)
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		if cfg.HTTP.Addr != ":8080" {
+			t.Errorf("Load() HTTP.Addr = %v, want %v", cfg.HTTP.Addr, ":8080")
+		}
+		if cfg.HTTP.ShutdownTimeout != 30*time.Second {
+			t.Errorf("Load() HTTP.ShutdownTimeout = %v, want %v", cfg.HTTP.ShutdownTimeout, 30*time.Second)
+		}
+		if cfg.HTTP.DrainDelay != 5*time.Second {
+			t.Errorf("Load() HTTP.DrainDelay = %v, want %v", cfg.HTTP.DrainDelay, 5*time.Second)
+		}
+	})
+
+	t.Run("from environment", func(t *testing.T) {
+		t.Setenv("HTTP_ADDR", "127.0.0.1:9090")
+		t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "5s")
+
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		if cfg.HTTP.Addr != "127.0.0.1:9090" {
+			t.Errorf("Load() HTTP.Addr = %v, want %v", cfg.HTTP.Addr, "127.0.0.1:9090")
+		}
+		if cfg.HTTP.ShutdownTimeout != 5*time.Second {
+			t.Errorf("Load() HTTP.ShutdownTimeout = %v, want %v", cfg.HTTP.ShutdownTimeout, 5*time.Second)
+		}
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		t.Setenv("HTTP_READ_TIMEOUT", "soon")
+
+		if _, err := config.Load(); err == nil {
+			t.Error("Load() error = nil, want error")
+		}
+	})
+
+	t.Run("middleware defaults", func(t *testing.T) {
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		if !cfg.HTTP.TrustRequestID || !cfg.HTTP.AccessLog {
+			t.Errorf("Load() HTTP = %+v, want request IDs trusted and access log enabled", cfg.HTTP)
+		}
+		if len(cfg.HTTP.CORS.AllowedOrigins) != 0 {
+			t.Errorf("Load() CORS.AllowedOrigins = %v, want CORS disabled", cfg.HTTP.CORS.AllowedOrigins)
+		}
+		if !slices.Contains(cfg.HTTP.CORS.AllowedHeaders, "X-API-Key") {
+			t.Errorf("Load() CORS.AllowedHeaders = %v, want X-API-Key allowed", cfg.HTTP.CORS.AllowedHeaders)
+		}
+	})
+
+	t.Run("CORS from environment", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+		t.Setenv("CORS_MAX_AGE", "1h")
+
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		want := []string{"https://a.example.com", "https://b.example.com"}
+		if !slices.Equal(cfg.HTTP.CORS.AllowedOrigins, want) {
+			t.Errorf("Load() CORS.AllowedOrigins = %v, want %v", cfg.HTTP.CORS.AllowedOrigins, want)
+		}
+		if cfg.HTTP.CORS.MaxAge != time.Hour {
+			t.Errorf("Load() CORS.MaxAge = %v, want %v", cfg.HTTP.CORS.MaxAge, time.Hour)
+		}
+	})
+
+	t.Run("CORS credentials with any origin", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+		if _, err := config.Load(); err == nil {
+			t.Error("Load() error = nil, want error")
+		}
+	})
+}
+
+func TestLoad_Log(t *testing.T) {
+	t.Setenv("STORAGE", config.StorageMemory)
+
+	tests := []struct {
+		name       string
+		level      string
+		format     string
+		wantLevel  slog.Level
+		wantFormat string
+		wantErr    bool
+	}{
+		{
+			name:       "defaults",
+			wantLevel:  slog.LevelInfo,
+			wantFormat: config.LogFormatJSON,
+		},
+		{
+			name:       "from environment",
+			level:      "debug",
+			format:     config.LogFormatText,
+			wantLevel:  slog.LevelDebug,
+			wantFormat: config.LogFormatText,
+		},
+		{
+			name:    "invalid level",
+			level:   "loud",
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tt.level)
+			t.Setenv("LOG_FORMAT", tt.format)
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if cfg.Log.Level != tt.wantLevel {
+				t.Errorf("Load() Log.Level = %v, want %v", cfg.Log.Level, tt.wantLevel)
+			}
+			if cfg.Log.Format != tt.wantFormat {
+				t.Errorf("Load() Log.Format = %v, want %v", cfg.Log.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestLoad_Trace(t *testing.T) {
+	t.Setenv("STORAGE", config.StorageMemory)
+
+	tests := []struct {
+		name         string
+		exporter     string
+		ratio        string
+		wantExporter string
+		wantRatio    float64
+		wantErr      bool
+	}{
+		{
+			name:         "defaults",
+			wantExporter: config.TraceExporterNone,
+			wantRatio:    1,
+		},
+		{
+			name:         "from environment",
+			exporter:     config.TraceExporterOTLP,
+			ratio:        "0.25",
+			wantExporter: config.TraceExporterOTLP,
+			wantRatio:    0.25,
+		},
+		{
+			name:     "invalid exporter",
+			exporter: "zipkin",
+			wantErr:  true,
+		},
+		{
+			name:    "ratio out of range",
+			ratio:   "2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRACE_EXPORTER", tt.exporter)
+			t.Setenv("TRACE_SAMPLE_RATIO", tt.ratio)
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if cfg.Trace.Exporter != tt.wantExporter {
+				t.Errorf("Load() Trace.Exporter = %v, want %v", cfg.Trace.Exporter, tt.wantExporter)
+			}
+			if cfg.Trace.SampleRatio != tt.wantRatio {
+				t.Errorf("Load() Trace.SampleRatio = %v, want %v", cfg.Trace.SampleRatio, tt.wantRatio)
+			}
+		})
+	}
+}
+
+func TestLoad_Auth(t *testing.T) {
+	t.Setenv("STORAGE", config.StorageMemory)
+
+	// SHA-256 of "test".
+	const hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name        string
+		apiKeys     string
+		secret      string
+		header      string
+		wantKeys    []string
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{
+			name:        "disabled by default",
+			wantEnabled: false,
+		},
+		{
+			name:        "API keys",
+			apiKeys:     "ci:" + hash + ", deploy:" + hash,
+			wantKeys:    []string{"ci", "deploy"},
+			wantEnabled: true,
+		},
+		{
+			name:        "JWT secret",
+			secret:      "secret",
+			wantEnabled: true,
+		},
+		{
+			name:        "identity header",
+			header:      "X-User-ID",
+			wantEnabled: false,
+		},
+		{
+			name:    "identity header with credentials",
+			secret:  "secret",
+			header:  "X-User-ID",
+			wantErr: true,
+		},
+		{
+			name:    "raw key instead of hash",
+			apiKeys: "ci:test",
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			apiKeys: hash,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AUTH_API_KEYS", tt.apiKeys)
+			t.Setenv("AUTH_JWT_SECRET", tt.secret)
+			t.Setenv("AUTH_IDENTITY_HEADER", tt.header)
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := cfg.Auth.Enabled(); got != tt.wantEnabled {
+				t.Errorf("Load() Auth.Enabled() = %v, want %v", got, tt.wantEnabled)
+			}
+
+			var names []string
+			for _, key := range cfg.Auth.APIKeys {
+				names = append(names, key.Name)
+			}
+			if !slices.Equal(names, tt.wantKeys) {
+				t.Errorf("Load() Auth.APIKeys names = %v, want %v", names, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestLoad_Todo(t *testing.T) {
+	t.Setenv("STORAGE", config.StorageMemory)
+
+	tests := []struct {
+		name          string
+		strict        string
+		retention     string
+		interval      string
+		wantStrict    bool
+		wantRetention time.Duration
+		wantInterval  time.Duration
+		wantErr       bool
+	}{
+		{name: "defaults", wantStrict: true, wantRetention: 720 * time.Hour, wantInterval: time.Hour},
+		{
+			name:          "from environment",
+			strict:        "false",
+			retention:     "0s",
+			interval:      "10m",
+			wantStrict:    false,
+			wantRetention: 0,
+			wantInterval:  10 * time.Minute,
+		},
+		{name: "invalid strict", strict: "sometimes", wantErr: true},
+		{name: "negative retention", retention: "-1h", wantErr: true},
+		{name: "zero interval", interval: "0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TODO_STRICT_SUBTASKS", tt.strict)
+			t.Setenv("TODO_TRASH_RETENTION", tt.retention)
+			t.Setenv("TODO_TRASH_PURGE_INTERVAL", tt.interval)
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if cfg.Todo.StrictSubtasks != tt.wantStrict {
+				t.Errorf("Load() Todo.StrictSubtasks = %v, want %v", cfg.Todo.StrictSubtasks, tt.wantStrict)
+			}
+			if cfg.Todo.TrashRetention != tt.wantRetention {
+				t.Errorf("Load() Todo.TrashRetention = %v, want %v", cfg.Todo.TrashRetention, tt.wantRetention)
+			}
+			if cfg.Todo.TrashPurgeInterval != tt.wantInterval {
+				t.Errorf("Load() Todo.TrashPurgeInterval = %v, want %v", cfg.Todo.TrashPurgeInterval, tt.wantInterval)
+			}
+		})
+	}
+}
+
 func TestGetEnvOrDefault(t *testing.T) {
 	// Test with environment variable set
 	os.Setenv("TEST_KEY", "test_value")
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+)
+
+// InTx runs fn in a transaction, with a store whose changes are committed if fn returns nil
+// and rolled back otherwise. InTx on that store runs fn in a savepoint of the transaction.
+// Errors of fn are returned as they are.
+func (db *DB) InTx(ctx context.Context, fn func(tx Storer) error) error {
+	var fnErr error
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		txDB := *db
+		txDB.conn = tx
+		fnErr = fn(&txDB)
+
+		return fnErr
+	}); err != nil {
+		if fnErr != nil {
+			return fnErr
+		}
+
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to run database transaction")
+	}
+
+	return nil
+}
+
+// InsertItems inserts new items and returns them as stored, in order. The inserts are sent in
+// pipelined batches instead of one round trip per item. If an item cannot be inserted none
+// are, and the items before it are returned with the error InsertItem would return for it;
+// if no single item failed, all of them are returned with the error.
+func (db *DB) InsertItems(ctx context.Context, items []Item) ([]Item, error) {
+	stored := make([]Item, 0, len(items))
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, item := range items {
+			batch.Queue(insertItemQuery, insertItemArgs(item)...)
+		}
+
+		results := tx.SendBatch(ctx, batch)
+		for range items {
+			var item Item
+			if err := results.QueryRow().Scan(itemFields(&item)...); err != nil {
+				results.Close()
+
+				return err
+			}
+			stored = append(stored, item)
+		}
+
+		if err := results.Close(); err != nil {
+			return err
+		}
+
+		// The tags and events need the IDs of the items.
+		batch = &pgx.Batch{}
+		actor := auditFrom(ctx).Actor
+		for i := range stored {
+			stored[i].Tags = sortedTags(items[i].Tags)
+			if len(stored[i].Tags) > 0 {
+				batch.Queue(insertTagsQuery, stored[i].Tags)
+				batch.Queue(insertTodoTagsQuery, stored[i].ID, stored[i].Tags)
+			}
+			batch.Queue(insertEventQuery, stored[i].ID, actor, ActionCreate, createChanges(stored[i]))
+		}
+
+		return tx.SendBatch(ctx, batch).Close()
+	}); err != nil {
+		return stored, insertError(err)
+	}
+
+	return stored, nil
+}
+
+// InTx runs fn with a copy of the store whose changes replace the store's if fn returns nil.
+// Errors of fn are returned as they are.
+//
+// Unlike Postgres, the store is locked until fn returns, so a large batch blocks every other
+// caller. This is intended: committing the copy replaces the whole store, so changes made by
+// others in the meantime would be lost. The memory store is meant for development and tests.
+func (m *Memory) InTx(_ context.Context, fn func(tx Storer) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := m.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	m.items, m.nextID, m.deletedWith = tx.items, tx.nextID, tx.deletedWith
+	m.events, m.nextEventID = tx.events, tx.nextEventID
+	m.lists, m.members, m.nextListID = tx.lists, tx.members, tx.nextListID
+
+	return nil
+}
+
+// clone returns a copy of the store that shares no mutable state with it. The caller must hold
+// the lock.
+func (m *Memory) clone() *Memory {
+	members := make(map[int64]map[string]string, len(m.members))
+	for listID, roles := range m.members {
+		members[listID] = maps.Clone(roles)
+	}
+
+	return &Memory{
+		items: maps.Clone(m.items),
+
+		nextID: m.nextID,
+
+		deletedWith: maps.Clone(m.deletedWith),
+
+		events: append([]Event(nil), m.events...),
+
+		nextEventID: m.nextEventID,
+
+		lists: maps.Clone(m.lists),
+
+		members: members,
+
+		nextListID: m.nextListID,
+	}
+}
+
+// InsertItems inserts new items and returns them as stored, in order. If an item cannot be
+// inserted none are, and the items before it are returned with the error InsertItem would
+// return for it.
+func (m *Memory) InsertItems(ctx context.Context, items []Item) ([]Item, error) {
+	var stored []Item
+	err := m.InTx(ctx, func(tx Storer) error {
+		for _, item := range items {
+			inserted, err := tx.InsertItem(ctx, item)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, inserted)
+		}
+
+		return nil
+	})
+
+	return stored, err
+}
@@ -0,0 +1,113 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryBatch(t *testing.T) {
+	testBatch(t, db.NewMemory())
+}
+
+func TestPostgresBatch(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testBatch(t, database)
+}
+
+// testBatch checks that InsertItems stores all items or none, and that InTx commits or rolls
+// back the changes of its function, with nested calls acting as savepoints.
+func testBatch(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	scope := db.Scope{OwnerID: "batch-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+	newItem := func(task string, tags ...string) db.Item {
+		return db.Item{OwnerID: scope.OwnerID, Task: task, Status: "TO_BE_STARTED", Tags: tags}
+	}
+
+	stored, err := store.InsertItems(ctx, []db.Item{newItem("buy paint", "diy"), newItem("paint the fence")})
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("InsertItems() = %+v, %v, want 2 items", stored, err)
+	}
+
+	if stored[0].ID == 0 || stored[0].Version != 1 || len(stored[0].Tags) != 1 || stored[1].Task != "paint the fence" {
+		t.Errorf("InsertItems() = %+v, want the items as stored", stored)
+	}
+
+	stored, err = store.InsertItems(ctx, []db.Item{newItem("mow the lawn"), newItem("buy paint"), newItem("rake leaves")})
+	if !errors.Is(err, apierror.ErrDuplicateTodo) || len(stored) != 1 {
+		t.Errorf("InsertItems() with a duplicate = %+v, %v, want the item before it and %v", stored, err, apierror.ErrDuplicateTodo)
+	}
+
+	count := func() int {
+		t.Helper()
+
+		items, err := store.ListItems(ctx, db.ListOptions{Scope: scope})
+		if err != nil {
+			t.Fatalf("ListItems() error = %v", err)
+		}
+
+		return len(items)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("InsertItems() with a duplicate stored %d items in total, want 2", got)
+	}
+
+	errRollback := errors.New("roll back")
+	err = store.InTx(ctx, func(tx db.Storer) error {
+		if _, err := tx.InsertItem(ctx, newItem("rake leaves")); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || count() != 2 {
+		t.Errorf("InTx() = %v with %d items, want %v and no new item", err, count(), errRollback)
+	}
+
+	err = store.InTx(ctx, func(tx db.Storer) error {
+		if _, err := tx.InsertItem(ctx, newItem("rake leaves")); err != nil {
+			return err
+		}
+
+		// A failing nested call only undoes its own changes.
+		nestedErr := tx.InTx(ctx, func(nested db.Storer) error {
+			if _, err := nested.InsertItem(ctx, newItem("wash the car")); err != nil {
+				return err
+			}
+
+			_, err := nested.InsertItem(ctx, newItem("buy paint"))
+
+			return err
+		})
+		if !errors.Is(nestedErr, apierror.ErrDuplicateTodo) {
+			t.Errorf("nested InTx() error = %v, want %v", nestedErr, apierror.ErrDuplicateTodo)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	items, err := store.ListItems(ctx, db.ListOptions{Scope: scope})
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+
+	tasks := map[string]bool{}
+	for _, item := range items {
+		tasks[item.Task] = true
+	}
+	if len(items) != 3 || !tasks["rake leaves"] || tasks["wash the car"] {
+		t.Errorf("ListItems() = %+v, want the committed item without the rolled back one", items)
+	}
+}
@@ -2,36 +2,290 @@ package db
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/brkcnr/golandworks-api/internal/apierror"
 	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Postgres error codes for constraint violations.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// parentConstraint is the foreign key from a subtask to its parent todo.
+const parentConstraint = "todo_items_parent_id_fkey"
+
+// Statuses that close an item, mirroring the service lifecycle. Closed subtasks are not open,
+// and cancelled ones do not count towards the completion of their parent.
+const (
+	statusDone      = "DONE"
+	statusCancelled = "CANCELLED"
+)
+
+// Columns that ListItems can sort by.
+const (
+	SortCreatedAt = "created_at"
+	SortTask      = "task"
+	SortStatus    = "status"
+	SortDueAt     = "due_at"
+	SortPriority  = "priority"
+)
+
+// DefaultPriority is the priority of items stored without one.
+const DefaultPriority = "normal"
+
+// priorityRank orders priorities from lowest to highest for sorting, mirroring the
+// CASE expression in sortColumns.
+var priorityRank = map[string]int{"low": 1, "normal": 2, "high": 3, "urgent": 4}
+
+// itemColumns lists the todo_items columns read into an Item, in itemFields order.
+// Tags are collected from todo_tags in name order, and the progress of subtasks from their rows
+// outside the trash.
+const itemColumns = `id, owner_id, COALESCE(list_id, 0), COALESCE(parent_id, 0), task, status, due_at, priority, notes,
+	created_at, deleted_at, version,
+	ARRAY(SELECT t.name FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.todo_id = todo_items.id ORDER BY t.name),
+	(SELECT count(*) FROM todo_items s WHERE s.parent_id = todo_items.id AND s.deleted_at IS NULL
+		AND s.status NOT IN ('` + statusDone + `', '` + statusCancelled + `')),
+	(SELECT (100 * count(*) FILTER (WHERE s.status = '` + statusDone + `') / NULLIF(count(*), 0))::int FROM todo_items s
+		WHERE s.parent_id = todo_items.id AND s.deleted_at IS NULL AND s.status <> '` + statusCancelled + `')`
+
 // Item is a todo item.
 type Item struct {
+	ID int64 `json:"id"`
+
+	// OwnerID identifies the caller the item belongs to, or that created it in a list.
+	// It is empty when callers are not identified.
+	OwnerID string `json:"owner_id,omitempty"`
+
+	// ListID is the list the item belongs to, or zero for a personal item of its owner.
+	ListID int64 `json:"list_id,omitempty"`
+
+	// ParentID is the item this one is a subtask of, or zero for a top-level item.
+	// It is set when the item is inserted and never changes.
+	ParentID int64 `json:"parent_id,omitempty"`
+
 	Task   string `json:"task"`
 	Status string `json:"status"`
+
+	// DueAt is when the item should be done, or nil if it has no deadline.
+	DueAt *time.Time `json:"due_at,omitempty"`
+
+	// Priority is low, normal, high or urgent. It is DefaultPriority if empty when stored.
+	Priority string `json:"priority"`
+
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt is when the item was moved to the trash, or nil if it is not in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Version counts the changes to the item, starting at 1. UpdateItem only changes an item
+	// at the version if it is not zero.
+	Version int64 `json:"version"`
+
+	// Tags label the item, sorted by name. Stored items always have a non-nil slice.
+	Tags []string `json:"tags"`
+
+	// OpenSubtasks counts the direct subtasks that are neither done nor cancelled.
+	OpenSubtasks int `json:"open_subtasks,omitempty"`
+
+	// Completion is the percentage of direct subtasks that are done, rounded down. Cancelled
+	// subtasks are not counted. It is nil if the item has no subtasks besides cancelled ones.
+	Completion *int `json:"completion,omitempty"`
+}
+
+// Scope selects the items an operation may see: the personal items of OwnerID, or the items
+// of a list if ListID is set.
+type Scope struct {
+	OwnerID string
+	ListID  int64
+}
+
+// ItemScope returns the scope that contains item.
+func ItemScope(item Item) Scope {
+	return Scope{OwnerID: item.OwnerID, ListID: item.ListID}
+}
+
+// Contains reports whether item is in the scope.
+func (s Scope) Contains(item Item) bool {
+	if s.ListID != 0 {
+		return item.ListID == s.ListID
+	}
+
+	return item.ListID == 0 && item.OwnerID == s.OwnerID
+}
+
+// condition returns a SQL condition selecting the scope's todo_items, using placeholder $n.
+func (s Scope) condition(n int) (string, any) {
+	if s.ListID != 0 {
+		return `list_id = $` + strconv.Itoa(n), s.ListID
+	}
+
+	return `owner_id = $` + strconv.Itoa(n) + ` AND list_id IS NULL`, s.OwnerID
+}
+
+// ListOptions filters, sorts and paginates ListItems.
+type ListOptions struct {
+	// Scope keeps only items in the scope.
+	Scope Scope
+
+	// AllOwners lists the items of every owner and list, ignoring Scope.
+	AllOwners bool
+
+	// Trashed lists the items in the trash instead of the items outside it.
+	Trashed bool
+
+	// Statuses keeps only items with one of the statuses; empty means any status.
+	Statuses []string
+
+	// ExcludeStatuses drops items with one of the statuses.
+	ExcludeStatuses []string
+
+	// Priorities keeps only items with one of the priorities; empty means any priority.
+	Priorities []string
+
+	// DueBefore and DueAfter keep only items due before, or at or after, the time.
+	// Zero times are ignored. Items without a due date never match them.
+	DueBefore time.Time
+	DueAfter  time.Time
+
+	// Tags keeps only items with any of the tags, or with all of them if AllTags is set.
+	// Empty means any tags. The tags must not repeat.
+	Tags    []string
+	AllTags bool
+
+	// Sort is one of the Sort constants; empty sorts by SortCreatedAt.
+	// Items without a due date sort last in either direction.
+	// Ties are broken by ID in the same direction.
+	Sort string
+
+	Desc bool
+
+	// Limit caps the number of items; zero means no limit.
+	Limit int
+
+	Offset int
+}
+
+// SearchOptions controls a full-text search over todo items.
+type SearchOptions struct {
+	// Scope keeps only items in the scope.
+	Scope Scope
+
+	// Query is matched against item tasks. Every word must match the start of a word in the task.
+	Query string
+
+	// Limit caps the number of results; zero means no limit.
+	Limit int
+
+	Offset int
+}
+
+// SearchResult is a todo item matching a search, with its relevance score.
+type SearchResult struct {
+	Item
+	Score float64 `json:"score"`
 }
 
 // DB is a database.
 type DB struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	tracer pgx.QueryTracer
+
+	// conn runs the queries: the pool, or the transaction of a DB passed to an InTx function.
+	conn querier
+}
+
+// querier is implemented by both pgxpool.Pool and pgx.Tx. Beginning a transaction on a
+// transaction creates a savepoint.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+}
+
+// Option is a function that configures a DB.
+type Option func(*DB)
+
+// WithLogger sets the logger for connection and migration events.
+func WithLogger(logger *slog.Logger) Option {
+	return func(db *DB) {
+		db.logger = logger
+	}
+}
+
+// WithQueryTracer sets a tracer that is called for every query on the pool's connections, and
+// for every batch if it also implements pgx.BatchTracer.
+func WithQueryTracer(tracer pgx.QueryTracer) Option {
+	return func(db *DB) {
+		db.tracer = tracer
+	}
 }
 
 // Storer is a database storer.
 type Storer interface {
-	InsertItem(ctx context.Context, item Item) error
+	InsertItem(ctx context.Context, item Item) (Item, error)
 	GetAllItems(ctx context.Context) ([]Item, error)
+	GetItem(ctx context.Context, scope Scope, id int64) (Item, error)
+	UpdateItem(ctx context.Context, item Item) (Item, error)
+	DeleteItem(ctx context.Context, scope Scope, id, version int64) error
+	RestoreItem(ctx context.Context, scope Scope, id int64) (Item, error)
+	PurgeItems(ctx context.Context, before time.Time) (int64, error)
+	SearchItems(ctx context.Context, opts SearchOptions) ([]SearchResult, error)
+	ListItems(ctx context.Context, opts ListOptions) ([]Item, error)
+	CountItems(ctx context.Context) (map[string]int64, error)
+	ListTags(ctx context.Context, scope Scope) ([]TagCount, error)
+	ListSubtasks(ctx context.Context, scope Scope, id int64, depth int) ([]Item, error)
+	ListEvents(ctx context.Context, scope Scope, id int64) ([]Event, error)
+	ListAllEvents(ctx context.Context, id int64) ([]Event, error)
+	InsertItems(ctx context.Context, items []Item) ([]Item, error)
+	InTx(ctx context.Context, fn func(tx Storer) error) error
+
+	InsertList(ctx context.Context, list List, ownerID string) (List, error)
+	GetList(ctx context.Context, id int64) (List, error)
+	ListLists(ctx context.Context, memberID string) ([]MemberList, error)
+	DeleteList(ctx context.Context, id int64) error
+	GetMember(ctx context.Context, listID int64, memberID string) (Member, error)
+	ListMembers(ctx context.Context, listID int64) ([]Member, error)
+	PutMember(ctx context.Context, member Member) (Member, error)
+	DeleteMember(ctx context.Context, listID int64, memberID string) error
 }
 
 // Compile time proof.
 var _ Storer = (*DB)(nil)
 
 // New creates a new database.
-func New(cfg config.DBConfig) (*DB, error) {
-	pool, err := pgxpool.New(context.Background(), cfg.ConnectionString())
+func New(cfg config.DBConfig, opts ...Option) (*DB, error) {
+	db := &DB{
+		logger: slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(db)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnectionString())
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusServiceUnavailable, "failed to connect to database")
+	}
+	poolConfig.ConnConfig.Tracer = db.tracer
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, apierror.Wrap(err, http.StatusServiceUnavailable, "failed to connect to database")
 	}
@@ -40,33 +294,437 @@ func New(cfg config.DBConfig) (*DB, error) {
 		return nil, apierror.Wrap(pingErr, http.StatusServiceUnavailable, "failed to ping database")
 	}
 
-	return &DB{pool: pool}, nil
+	db.pool = pool
+	db.conn = pool
+
+	db.logger.Info("connected to database", "url", cfg.SafeConnectionString())
+
+	return db, nil
 }
 
-// InsertItem inserts a new item into the database.
-func (db *DB) InsertItem(ctx context.Context, item Item) error {
-	query := `INSERT INTO todo_items (task, status) VALUES ($1, $2)`
-	_, err := db.pool.Exec(ctx, query, item.Task, item.Status)
-	if err != nil {
-		return apierror.Wrap(err, http.StatusInternalServerError, "failed to insert item into database")
+// Ping checks that the database is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	if err := db.pool.Ping(ctx); err != nil {
+		return apierror.Wrap(err, http.StatusServiceUnavailable, "failed to ping database")
 	}
 
 	return nil
 }
 
-// GetAllItems gets all items from the database.
+// InsertItem inserts a new item into the database and returns it as stored.
+// It returns apierror.ErrDuplicateTodo if the item's scope already has the task, and
+// apierror.ErrNotFound if its parent is in the trash or outside its scope.
+func (db *DB) InsertItem(ctx context.Context, item Item) (Item, error) {
+	tags := sortedTags(item.Tags)
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		if item.ParentID != 0 {
+			// The lock keeps the parent from being trashed before the subtask is inserted.
+			condition, arg := ItemScope(item).condition(2)
+			var parentID int64
+			if err := tx.QueryRow(ctx, `SELECT id FROM todo_items
+				WHERE id = $1 AND deleted_at IS NULL AND `+condition+` FOR SHARE`, item.ParentID, arg).
+				Scan(&parentID); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return errParentNotFound
+				}
+
+				return err
+			}
+		}
+
+		if err := tx.QueryRow(ctx, insertItemQuery, insertItemArgs(item)...).Scan(itemFields(&item)...); err != nil {
+			return err
+		}
+		item.Tags = tags
+
+		if err := setTags(ctx, tx, item.ID, tags); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, item.ID, ActionCreate, createChanges(item))
+	}); err != nil {
+		return Item{}, insertError(err)
+	}
+
+	return item, nil
+}
+
+// insertItemQuery inserts an item with insertItemArgs and returns its itemColumns.
+const insertItemQuery = `INSERT INTO todo_items (owner_id, list_id, parent_id, task, status, due_at, priority, notes)
+	VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, COALESCE(NULLIF($7, ''), '` + DefaultPriority + `'), $8)
+	RETURNING ` + itemColumns
+
+// insertItemArgs returns the arguments of insertItemQuery for item.
+func insertItemArgs(item Item) []any {
+	return []any{item.OwnerID, item.ListID, item.ParentID, item.Task, item.Status, item.DueAt, item.Priority, item.Notes}
+}
+
+// errParentNotFound is returned for a subtask whose parent is missing, in the trash or outside
+// the subtask's scope.
+var errParentNotFound = apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "parent todo item not found")
+
+// insertError maps an error inserting an item to the error InsertItem returns.
+func insertError(err error) error {
+	if isUniqueViolation(err) {
+		return apierror.ErrDuplicateTodo
+	}
+
+	if errors.Is(err, errParentNotFound) {
+		return errParentNotFound
+	}
+
+	if isForeignKeyViolation(err) {
+		if constraintName(err) == parentConstraint {
+			return errParentNotFound
+		}
+
+		return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+	}
+
+	return apierror.Wrap(err, http.StatusInternalServerError, "failed to insert item into database")
+}
+
+// GetAllItems gets the items of every owner outside the trash from the database.
 func (db *DB) GetAllItems(ctx context.Context) ([]Item, error) {
-	query := `SELECT task, status FROM todo_items`
-	rows, err := db.pool.Query(ctx, query)
+	return db.queryItems(ctx, `SELECT `+itemColumns+` FROM todo_items WHERE deleted_at IS NULL ORDER BY id`)
+}
+
+// ListItems gets a filtered, sorted page of items from the database.
+func (db *DB) ListItems(ctx context.Context, opts ListOptions) ([]Item, error) {
+	column, ok := sortColumns[opts.Sort]
+	if !ok {
+		return nil, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid sort column")
+	}
+
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
+	}
+
+	args := []any{opts.Limit, opts.Offset}
+	param := func(value any) string {
+		args = append(args, value)
+
+		return `$` + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{`deleted_at IS NULL`}
+	if opts.Trashed {
+		conditions[0] = `deleted_at IS NOT NULL`
+	}
+
+	if len(opts.Statuses) > 0 {
+		conditions = append(conditions, `status = ANY(`+param(opts.Statuses)+`)`)
+	}
+
+	if len(opts.ExcludeStatuses) > 0 {
+		conditions = append(conditions, `status <> ALL(`+param(opts.ExcludeStatuses)+`)`)
+	}
+
+	if len(opts.Priorities) > 0 {
+		conditions = append(conditions, `priority = ANY(`+param(opts.Priorities)+`)`)
+	}
+
+	if !opts.DueBefore.IsZero() {
+		conditions = append(conditions, `due_at < `+param(opts.DueBefore))
+	}
+
+	if !opts.DueAfter.IsZero() {
+		conditions = append(conditions, `due_at >= `+param(opts.DueAfter))
+	}
+
+	if len(opts.Tags) > 0 {
+		tags := param(opts.Tags)
+		matching := `SELECT count(*) FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id
+			WHERE tt.todo_id = todo_items.id AND t.name = ANY(` + tags + `)`
+		if opts.AllTags {
+			conditions = append(conditions, `(`+matching+`) = cardinality(`+tags+`::text[])`)
+		} else {
+			conditions = append(conditions, `(`+matching+`) > 0`)
+		}
+	}
+
+	if !opts.AllOwners {
+		condition, arg := opts.Scope.condition(len(args) + 1)
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	// The sort column comes from the sortColumns allow-list, never from user input.
+	query := `SELECT ` + itemColumns + ` FROM todo_items
+		WHERE ` + strings.Join(conditions, ` AND `) + `
+		ORDER BY ` + column + ` ` + direction + ` NULLS LAST, id ` + direction + `
+		LIMIT NULLIF($1, 0) OFFSET $2`
+
+	return db.queryItems(ctx, query, args...)
+}
+
+// sortColumns maps ListOptions.Sort values to todo_items columns.
+var sortColumns = map[string]string{
+	"":            "created_at",
+	SortCreatedAt: "created_at",
+	SortTask:      "task",
+	SortStatus:    "status",
+	SortDueAt:     "due_at",
+	SortPriority:  `CASE priority WHEN 'low' THEN 1 WHEN 'normal' THEN 2 WHEN 'high' THEN 3 WHEN 'urgent' THEN 4 END`,
+}
+
+// CountItems returns the number of items outside the trash in each status. Statuses without
+// items are omitted.
+func (db *DB) CountItems(ctx context.Context) (map[string]int64, error) {
+	rows, err := db.conn.Query(ctx, `SELECT status, count(*) FROM todo_items WHERE deleted_at IS NULL GROUP BY status`)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to count items")
+	}
+
+	counts := make(map[string]int64)
+
+	var (
+		status string
+		count  int64
+	)
+	if _, err = pgx.ForEachRow(rows, []any{&status, &count}, func() error {
+		counts[status] = count
+
+		return nil
+	}); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to count items")
+	}
+
+	return counts, nil
+}
+
+// Stat returns connection pool statistics.
+func (db *DB) Stat() *pgxpool.Stat {
+	return db.pool.Stat()
+}
+
+// GetItem gets a single item in the scope by its ID. Items outside the scope or in the trash
+// are not found.
+func (db *DB) GetItem(ctx context.Context, scope Scope, id int64) (Item, error) {
+	condition, arg := scope.condition(2)
+	query := `SELECT ` + itemColumns + ` FROM todo_items WHERE id = $1 AND deleted_at IS NULL AND ` + condition
+
+	var item Item
+	if err := db.conn.QueryRow(ctx, query, id, arg).Scan(itemFields(&item)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+		}
+
+		return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return item, nil
+}
+
+// UpdateItem overwrites the task, status, due date, priority and notes of an existing item in
+// the scope of item and returns it as stored. Items in the trash are not found. It returns
+// apierror.ErrPreconditionFailed if item.Version is set and the stored item is at another
+// version, and apierror.ErrDuplicateTodo if another item in the scope has the task.
+func (db *DB) UpdateItem(ctx context.Context, item Item) (Item, error) {
+	condition, arg := ItemScope(item).condition(2)
+	query := `UPDATE todo_items
+		SET task = $3, status = $4, due_at = $5, priority = COALESCE(NULLIF($6, ''), '` + DefaultPriority + `'), notes = $7,
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ` + condition + ` RETURNING ` + itemColumns
+
+	var before, updated Item
+	tags := sortedTags(item.Tags)
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `SELECT `+itemColumns+` FROM todo_items
+			WHERE id = $1 AND deleted_at IS NULL AND `+condition+` FOR UPDATE`, item.ID, arg).
+			Scan(itemFields(&before)...); err != nil {
+			return err
+		}
+
+		if item.Version != 0 && item.Version != before.Version {
+			return apierror.ErrPreconditionFailed
+		}
+
+		if err := tx.QueryRow(ctx, query, item.ID, arg, item.Task, item.Status, item.DueAt, item.Priority, item.Notes).
+			Scan(itemFields(&updated)...); err != nil {
+			return err
+		}
+		updated.Tags = tags
+
+		if err := setTags(ctx, tx, item.ID, tags); err != nil {
+			return err
+		}
+
+		changes, err := updateChanges(before, updated)
+		if err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, item.ID, updateAction(ctx), changes)
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+		}
+
+		if errors.Is(err, apierror.ErrPreconditionFailed) {
+			return Item{}, err
+		}
+
+		if isUniqueViolation(err) {
+			return Item{}, apierror.ErrDuplicateTodo
+		}
+
+		return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to update item in database")
+	}
+
+	return updated, nil
+}
+
+// setTags replaces the tags of an item, creating tags that do not exist yet.
+func setTags(ctx context.Context, tx pgx.Tx, itemID int64, tags []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM todo_tags WHERE todo_id = $1`, itemID); err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, insertTagsQuery, tags); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, insertTodoTagsQuery, itemID, tags)
+
+	return err
+}
+
+// Queries of setTags that create the tags in $1 and attach the tags in $2 to the item $1.
+const (
+	insertTagsQuery     = `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+	insertTodoTagsQuery = `INSERT INTO todo_tags (todo_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`
+)
+
+// sortedTags returns a sorted copy of tags, which is empty rather than nil.
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	slices.Sort(sorted)
+
+	return sorted
+}
+
+// DeleteItem moves an item in the scope to the trash by its ID, together with its subtasks
+// outside the trash. Items already in the trash are not found. If version is not zero, it
+// returns apierror.ErrPreconditionFailed unless the item is at that version.
+func (db *DB) DeleteItem(ctx context.Context, scope Scope, id, version int64) error {
+	condition, arg := scope.condition(2)
+
+	// The subtasks are marked as deleted with the item, so they are restored with it.
+	query := `WITH RECURSIVE tree (id) AS (
+			SELECT id FROM todo_items WHERE id = $1 AND deleted_at IS NULL AND ` + condition + `
+			UNION ALL
+			SELECT s.id FROM todo_items s JOIN tree ON s.parent_id = tree.id WHERE s.deleted_at IS NULL
+		)
+		UPDATE todo_items SET deleted_at = now(), deleted_with = $1, version = version + 1
+		WHERE id IN (SELECT id FROM tree)
+		RETURNING id, deleted_at`
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var current int64
+		if err := tx.QueryRow(ctx, `SELECT version FROM todo_items WHERE id = $1 AND deleted_at IS NULL AND `+
+			condition+` FOR UPDATE`, id, arg).Scan(&current); err != nil {
+			return err
+		}
+
+		if version != 0 && version != current {
+			return apierror.ErrPreconditionFailed
+		}
+
+		rows, err := tx.Query(ctx, query, id, arg)
+		if err != nil {
+			return err
+		}
+
+		var deleted []int64
+		var deletedAt time.Time
+		var treeID int64
+		if _, err = pgx.ForEachRow(rows, []any{&treeID, &deletedAt}, func() error {
+			deleted = append(deleted, treeID)
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		slices.Sort(deleted)
+		for _, treeID := range deleted {
+			if err = insertEvent(ctx, tx, treeID, ActionDelete, trashChanges(nil, &deletedAt)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+		}
+
+		if errors.Is(err, apierror.ErrPreconditionFailed) {
+			return err
+		}
+
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete item from database")
+	}
+
+	return nil
+}
+
+// SearchItems finds items in the scope outside the trash matching the query using the
+// full-text index, most relevant first.
+func (db *DB) SearchItems(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	terms := searchTerms(opts.Query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	// Each term becomes a prefix match, so "buy gro" matches "Buy groceries".
+	tsQuery := strings.Join(terms, ":* & ") + ":*"
+
+	condition, arg := opts.Scope.condition(4)
+	query := `SELECT ` + itemColumns + `, ts_rank(search_vector, q) AS score
+		FROM todo_items, to_tsquery('simple', $1) q
+		WHERE ` + condition + ` AND deleted_at IS NULL AND search_vector @@ q
+		ORDER BY score DESC, id
+		LIMIT NULLIF($2, 0) OFFSET $3`
+	rows, err := db.conn.Query(ctx, query, tsQuery, opts.Limit, opts.Offset, arg)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to search database")
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		if scanErr := rows.Scan(append(itemFields(&result.Item), &result.Score)...); scanErr != nil {
+			return nil, apierror.Wrap(scanErr, http.StatusInternalServerError, "failed to scan database row")
+		}
+		results = append(results, result)
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, apierror.Wrap(rowsErr, http.StatusInternalServerError, "error iterating database rows")
+	}
+
+	return results, nil
+}
+
+// queryItems runs a query selecting itemColumns and collects the rows.
+func (db *DB) queryItems(ctx context.Context, query string, args ...any) ([]Item, error) {
+	rows, err := db.conn.Query(ctx, query, args...)
 	if err != nil {
 		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
 	}
 	defer rows.Close()
 
-	var items []Item
+	items := []Item{}
 	for rows.Next() {
 		var item Item
-		if scanErr := rows.Scan(&item.Task, &item.Status); scanErr != nil {
+		if scanErr := rows.Scan(itemFields(&item)...); scanErr != nil {
 			return nil, apierror.Wrap(scanErr, http.StatusInternalServerError, "failed to scan database row")
 		}
 		items = append(items, item)
@@ -79,6 +737,48 @@ func (db *DB) GetAllItems(ctx context.Context) ([]Item, error) {
 	return items, nil
 }
 
+// itemFields returns scan destinations for the columns in itemColumns.
+func itemFields(item *Item) []any {
+	return []any{
+		&item.ID, &item.OwnerID, &item.ListID, &item.ParentID, &item.Task, &item.Status,
+		&item.DueAt, &item.Priority, &item.Notes, &item.CreatedAt, &item.DeletedAt, &item.Version,
+		&item.Tags,
+		&item.OpenSubtasks, &item.Completion,
+	}
+}
+
+// searchTerms splits a search query into lower-cased words, dropping punctuation
+// the same way the 'simple' text search configuration does.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key constraint violation.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// constraintName returns the name of the constraint a Postgres error violated, or "".
+func constraintName(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+
+	return ""
+}
+
 // Close closes the database.
 func (db *DB) Close() {
 	db.pool.Close()
@@ -2,10 +2,16 @@ package db_test
 
 import (
 	"context"
+	"errors"
 	"os"
+	"reflect"
+	"slices"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/brkcnr/golandworks-api/internal/apierror"
 	"github.com/brkcnr/golandworks-api/internal/config"
 	"github.com/brkcnr/golandworks-api/internal/db"
 )
@@ -32,6 +38,11 @@ func setupTestDB(t *testing.T) (*db.DB, func()) {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
+	if _, err = database.MigrateUp(context.Background()); err != nil {
+		database.Close()
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
 	// Return cleanup function
 	cleanup := func() {
 		database.Close()
@@ -53,6 +64,9 @@ func TestInsertAndGetItems(t *testing.T) {
 
 	ctx := context.Background()
 
+	// Tasks are unique, so suffix them to allow re-running against the same database
+	suffix := " " + strconv.FormatInt(time.Now().UnixNano(), 10)
+
 	// Test cases
 	testCases := []struct {
 		name     string
@@ -62,7 +76,7 @@ func TestInsertAndGetItems(t *testing.T) {
 		{
 			name: "Valid item",
 			item: db.Item{
-				Task:   "Test task",
+				Task:   "Test task" + suffix,
 				Status: "pending",
 			},
 			wantErr: false,
@@ -70,7 +84,7 @@ func TestInsertAndGetItems(t *testing.T) {
 		{
 			name: "Another valid item",
 			item: db.Item{
-				Task:   "Another test task",
+				Task:   "Another test task" + suffix,
 				Status: "completed",
 			},
 			wantErr: false,
@@ -80,7 +94,7 @@ func TestInsertAndGetItems(t *testing.T) {
 	// Insert items
 	for _, tc := range testCases {
 		t.Run("Insert "+tc.name, func(t *testing.T) {
-			err := database.InsertItem(ctx, tc.item)
+			_, err := database.InsertItem(ctx, tc.item)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("InsertItem() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -115,4 +129,373 @@ func TestInsertAndGetItems(t *testing.T) {
 			}
 		}
 	})
-} 
\ No newline at end of file
+} 
+func TestPostgresStorer(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testStorer(t, database)
+}
+
+func TestPostgresConcurrentDuplicateInserts(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testConcurrentDuplicateInserts(t, database)
+}
+
+// testConcurrentDuplicateInserts checks that only one of many concurrent inserts of
+// the same task succeeds and the rest fail with apierror.ErrDuplicateTodo.
+func testConcurrentDuplicateInserts(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	task := "Concurrent task " + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	const workers = 20
+
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.InsertItem(ctx, db.Item{Task: task, Status: "TO_BE_STARTED"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var inserted int
+	for err := range errs {
+		switch {
+		case err == nil:
+			inserted++
+		case !errors.Is(err, apierror.ErrDuplicateTodo):
+			t.Errorf("InsertItem() error = %v, want %v", err, apierror.ErrDuplicateTodo)
+		}
+	}
+
+	if inserted != 1 {
+		t.Errorf("InsertItem() succeeded %d times, want 1", inserted)
+	}
+}
+
+// testStorer checks the Storer contract shared by every implementation.
+// Task names are unique per run so it can be used against a shared database.
+func testStorer(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	owner, stranger := "owner-"+suffix, "stranger-"+suffix
+	ownerScope, strangerScope := db.Scope{OwnerID: owner}, db.Scope{OwnerID: stranger}
+
+	inserted, err := store.InsertItem(ctx, db.Item{OwnerID: owner, Task: "Storer task " + suffix, Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+	if inserted.ID == 0 {
+		t.Fatal("InsertItem() did not assign an ID")
+	}
+
+	got, err := store.GetItem(ctx, ownerScope, inserted.ID)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, inserted) {
+		t.Errorf("GetItem() = %v, want %v", got, inserted)
+	}
+
+	if _, err = store.GetItem(ctx, strangerScope, inserted.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() by another owner error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	items, err := store.GetAllItems(ctx)
+	if err != nil {
+		t.Fatalf("GetAllItems() error = %v", err)
+	}
+	if !slices.ContainsFunc(items, func(item db.Item) bool { return reflect.DeepEqual(item, inserted) }) {
+		t.Errorf("GetAllItems() did not return inserted item %v", inserted)
+	}
+
+	results, err := store.SearchItems(ctx, db.SearchOptions{Scope: ownerScope, Query: "stor " + suffix, Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchItems() error = %v", err)
+	}
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Item, inserted) || results[0].Score <= 0 {
+		t.Errorf("SearchItems() = %v, want only %v with a positive score", results, inserted)
+	}
+
+	if _, err = store.InsertItem(ctx, inserted); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Errorf("InsertItem() of existing task error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	// Tasks are unique per owner, so another owner can have the same task.
+	strangers, err := store.InsertItem(ctx, db.Item{OwnerID: stranger, Task: inserted.Task, Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() of another owner's task error = %v", err)
+	}
+
+	results, err = store.SearchItems(ctx, db.SearchOptions{Scope: strangerScope, Query: "stor " + suffix, Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchItems() error = %v", err)
+	}
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Item, strangers) {
+		t.Errorf("SearchItems() by another owner = %v, want only %v", results, strangers)
+	}
+
+	other, err := store.InsertItem(ctx, db.Item{OwnerID: owner, Task: "Other storer task " + suffix, Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+	other.Task = inserted.Task
+	if _, err = store.UpdateItem(ctx, other); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Errorf("UpdateItem() to existing task error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	inserted.Status = "IN_PROGRESS"
+	if _, err = store.UpdateItem(ctx, inserted); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	hijacked := inserted
+	hijacked.OwnerID = stranger
+	if _, err = store.UpdateItem(ctx, hijacked); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("UpdateItem() by another owner error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	got, err = store.GetItem(ctx, ownerScope, inserted.ID)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if got.Status != "IN_PROGRESS" {
+		t.Errorf("GetItem() status = %v, want %v", got.Status, "IN_PROGRESS")
+	}
+
+	// A status unique to this run isolates the items from others in a shared database.
+	listStatus := "LISTED_" + suffix
+	for _, task := range []string{"List a " + suffix, "List b " + suffix, "List c " + suffix} {
+		if _, err = store.InsertItem(ctx, db.Item{OwnerID: owner, Task: task, Status: listStatus}); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+	if _, err = store.InsertItem(ctx, db.Item{OwnerID: stranger, Task: "List d " + suffix, Status: listStatus}); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	listed, err := store.ListItems(ctx, db.ListOptions{
+		Scope:    ownerScope,
+		Statuses: []string{listStatus},
+		Sort:     db.SortTask,
+		Desc:     true,
+		Limit:    2,
+		Offset:   1,
+	})
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+	if len(listed) != 2 || listed[0].Task != "List b "+suffix || listed[1].Task != "List a "+suffix {
+		t.Errorf("ListItems() = %v, want List b and List a", listed)
+	}
+
+	listed, err = store.ListItems(ctx, db.ListOptions{AllOwners: true, Statuses: []string{listStatus}})
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+	if len(listed) != 4 {
+		t.Errorf("ListItems() of all owners returned %d items, want 4", len(listed))
+	}
+
+	counts, err := store.CountItems(ctx)
+	if err != nil {
+		t.Fatalf("CountItems() error = %v", err)
+	}
+	if counts[listStatus] != 4 {
+		t.Errorf("CountItems()[%s] = %d, want 4", listStatus, counts[listStatus])
+	}
+
+	if err = store.DeleteItem(ctx, strangerScope, inserted.ID, 0); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("DeleteItem() by another owner error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if err = store.DeleteItem(ctx, ownerScope, inserted.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	if _, err = store.GetItem(ctx, ownerScope, inserted.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() after delete error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err = store.UpdateItem(ctx, inserted); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("UpdateItem() after delete error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if err = store.DeleteItem(ctx, ownerScope, inserted.ID, 0); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("DeleteItem() after delete error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := db.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Migrations() returned no migrations")
+	}
+
+	for i, m := range migrations {
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d_%s is missing its up or down script", m.Version, m.Name)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d is not ordered after %d", m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	latest, found, err := database.MigrateDown(ctx)
+	if err != nil {
+		t.Fatalf("MigrateDown() error = %v", err)
+	}
+	if !found {
+		t.Fatal("MigrateDown() found no applied migration")
+	}
+
+	statuses, err := database.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus() error = %v", err)
+	}
+	for _, s := range statuses {
+		if s.Version == latest.Version && s.AppliedAt != nil {
+			t.Errorf("MigrationStatus() reports %d as applied after rollback", s.Version)
+		}
+	}
+
+	applied, err := database.MigrateUp(ctx)
+	if err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != latest.Version {
+		t.Errorf("MigrateUp() applied %v, want only %d", applied, latest.Version)
+	}
+}
+
+func TestPostgresItemDetails(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testItemDetails(t, database)
+}
+
+// testItemDetails checks that due dates, priorities and notes are stored, filtered and sorted.
+func testItemDetails(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	scope := db.Scope{OwnerID: "details-" + suffix}
+	now := time.Date(2030, 1, 10, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		due := now.AddDate(0, 0, days)
+
+		return &due
+	}
+
+	seed := []db.Item{
+		{Task: "past", Status: "TO_BE_STARTED", DueAt: at(-2), Priority: "high", Notes: "call first"},
+		{Task: "past done", Status: "DONE", DueAt: at(-1), Priority: "urgent"},
+		{Task: "future", Status: "IN_PROGRESS", DueAt: at(3), Priority: "low"},
+		{Task: "no deadline", Status: "TO_BE_STARTED"},
+	}
+	for _, item := range seed {
+		item.OwnerID = scope.OwnerID
+		if _, err := store.InsertItem(ctx, item); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts db.ListOptions
+		want []string
+	}{
+		{
+			name: "default priority",
+			opts: db.ListOptions{Priorities: []string{db.DefaultPriority}},
+			want: []string{"no deadline"},
+		},
+		{
+			name: "overdue",
+			opts: db.ListOptions{DueBefore: now, ExcludeStatuses: []string{"DONE", "CANCELLED"}},
+			want: []string{"past"},
+		},
+		{
+			name: "due after",
+			opts: db.ListOptions{DueAfter: *at(-1)},
+			want: []string{"past done", "future"},
+		},
+		{
+			name: "by due date",
+			opts: db.ListOptions{Sort: db.SortDueAt},
+			want: []string{"past", "past done", "future", "no deadline"},
+		},
+		{
+			name: "by due date descending",
+			opts: db.ListOptions{Sort: db.SortDueAt, Desc: true},
+			want: []string{"future", "past done", "past", "no deadline"},
+		},
+		{
+			name: "by priority descending",
+			opts: db.ListOptions{Sort: db.SortPriority, Desc: true},
+			want: []string{"past done", "past", "no deadline", "future"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Scope = scope
+
+			items, err := store.ListItems(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("ListItems() error = %v", err)
+			}
+
+			var tasks []string
+			for _, item := range items {
+				tasks = append(tasks, item.Task)
+			}
+			if !slices.Equal(tasks, tt.want) {
+				t.Errorf("ListItems() = %v, want %v", tasks, tt.want)
+			}
+		})
+	}
+
+	items, err := store.ListItems(ctx, db.ListOptions{Scope: scope, Sort: db.SortTask})
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+
+	past := items[2]
+	if past.Task != "past" || past.DueAt == nil || !past.DueAt.Equal(*at(-2)) || past.Notes != "call first" {
+		t.Fatalf("stored item = %+v, want the seeded details", past)
+	}
+
+	past.DueAt, past.Priority, past.Notes = nil, "", ""
+	updated, err := store.UpdateItem(ctx, past)
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if updated.DueAt != nil || updated.Priority != db.DefaultPriority || updated.Notes != "" {
+		t.Errorf("UpdateItem() = %+v, want cleared details and the default priority", updated)
+	}
+}
@@ -0,0 +1,324 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+)
+
+// Actions recorded in the change history of items.
+const (
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionTransition = "transition"
+	ActionDelete     = "delete"
+	ActionRestore    = "restore"
+
+	// ActionPurge is recorded when an item is removed for good: purged from the trash or
+	// deleted with its list. It changes no fields.
+	ActionPurge = "purge"
+)
+
+// Event is an entry in the change history of an item.
+type Event struct {
+	ID     int64 `json:"id"`
+	TodoID int64 `json:"todo_id"`
+
+	// Actor identifies the caller that made the change. It is empty when callers are not identified.
+	Actor string `json:"actor"`
+
+	// Action is one of ActionCreate, ActionUpdate, ActionTransition, ActionDelete, ActionRestore
+	// or ActionPurge.
+	Action string `json:"action"`
+
+	// Changes maps the JSON name of each field the event changed to its values before and after.
+	Changes   map[string]Change `json:"changes"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Change is the value of a field before and after an event, as decoded from JSON.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Audit describes the changes made with a context, for their events.
+type Audit struct {
+	// Actor identifies the caller making the changes.
+	Actor string
+
+	// Action is recorded by UpdateItem, for example ActionTransition. It defaults to
+	// ActionUpdate; the other changes always record their own action.
+	Action string
+}
+
+// auditKey is the context key of the Audit.
+type auditKey struct{}
+
+// WithAudit returns a context whose item changes are recorded as described by audit.
+// Changes made with a context without an Audit are recorded without an actor.
+func WithAudit(ctx context.Context, audit Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, audit)
+}
+
+// auditFrom returns the Audit of ctx.
+func auditFrom(ctx context.Context) Audit {
+	audit, _ := ctx.Value(auditKey{}).(Audit)
+
+	return audit
+}
+
+// updateAction returns the action recorded by UpdateItem for ctx.
+func updateAction(ctx context.Context) string {
+	if action := auditFrom(ctx).Action; action != "" {
+		return action
+	}
+
+	return ActionUpdate
+}
+
+// auditFields lists the item fields recorded in change history by their JSON names.
+var auditFields = []struct {
+	name  string
+	value func(Item) any
+}{
+	{"task", func(item Item) any { return item.Task }},
+	{"status", func(item Item) any { return item.Status }},
+	{"due_at", func(item Item) any { return item.DueAt }},
+	{"priority", func(item Item) any { return item.Priority }},
+	{"notes", func(item Item) any { return item.Notes }},
+	{"tags", func(item Item) any { return item.Tags }},
+}
+
+// createChanges returns the changes recorded when item is created: every audited field, with no
+// value before.
+func createChanges(item Item) map[string]Change {
+	changes := make(map[string]Change, len(auditFields))
+	for _, field := range auditFields {
+		changes[field.name] = Change{After: field.value(item)}
+	}
+
+	return changes
+}
+
+// updateChanges returns the audited fields that differ between two versions of an item.
+// Values are compared by their JSON encoding, which is how they are recorded.
+func updateChanges(before, after Item) (map[string]Change, error) {
+	changes := make(map[string]Change)
+	for _, field := range auditFields {
+		b, a := field.value(before), field.value(after)
+
+		bJSON, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		aJSON, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(bJSON, aJSON) {
+			changes[field.name] = Change{Before: b, After: a}
+		}
+	}
+
+	return changes, nil
+}
+
+// trashChanges returns the changes recorded when an item moves into or out of the trash.
+func trashChanges(before, after *time.Time) map[string]Change {
+	return map[string]Change{"deleted_at": {Before: before, After: after}}
+}
+
+// insertEventQuery records an event of the item $1 by the actor $2.
+const insertEventQuery = `INSERT INTO todo_events (todo_id, actor, action, changes) VALUES ($1, $2, $3, $4)`
+
+// insertEvent records an event for an item in the transaction of its change.
+func insertEvent(ctx context.Context, tx pgx.Tx, todoID int64, action string, changes map[string]Change) error {
+	_, err := tx.Exec(ctx, insertEventQuery, todoID, auditFrom(ctx).Actor, action, changes)
+
+	return err
+}
+
+// insertPurgeEvents records an ActionPurge event for each of the items removed for good in the
+// transaction, in one round trip.
+func insertPurgeEvents(ctx context.Context, tx pgx.Tx, todoIDs []int64) error {
+	if len(todoIDs) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	actor := auditFrom(ctx).Actor
+	for _, todoID := range todoIDs {
+		batch.Queue(insertEventQuery, todoID, actor, ActionPurge, map[string]Change{})
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// deletedIDs collects the IDs returned by a DELETE ... RETURNING id query, in order.
+func deletedIDs(rows pgx.Rows) ([]int64, error) {
+	ids := []int64{}
+
+	var id int64
+	_, err := pgx.ForEachRow(rows, []any{&id}, func() error {
+		ids = append(ids, id)
+
+		return nil
+	})
+
+	return ids, err
+}
+
+// ListEvents gets the change history of an item in the scope, oldest first. Items in the trash
+// have a history; items outside the scope or purged from the trash are not found.
+func (db *DB) ListEvents(ctx context.Context, scope Scope, id int64) ([]Event, error) {
+	condition, arg := scope.condition(2)
+
+	var exists bool
+	if err := db.conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM todo_items WHERE id = $1 AND `+condition+`)`,
+		id, arg).Scan(&exists); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	if !exists {
+		return nil, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	return db.events(ctx, id)
+}
+
+// ListAllEvents gets the change history of an item of any owner or list, oldest first. Items
+// purged from the trash or deleted with their list have a history; items without one are not
+// found.
+func (db *DB) ListAllEvents(ctx context.Context, id int64) ([]Event, error) {
+	events, err := db.events(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	return events, nil
+}
+
+// events gets the events of an item, oldest first.
+func (db *DB) events(ctx context.Context, id int64) ([]Event, error) {
+	rows, err := db.conn.Query(ctx, `SELECT id, todo_id, actor, action, changes, created_at
+		FROM todo_events WHERE todo_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	events := []Event{}
+	var event Event
+	if _, err = pgx.ForEachRow(rows, []any{&event.ID, &event.TodoID, &event.Actor, &event.Action, &event.Changes,
+		&event.CreatedAt}, func() error {
+		events = append(events, event)
+		event.Changes = nil
+
+		return nil
+	}); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return events, nil
+}
+
+// recordEvent appends an event for an item to the history. Changes are stored as decoded from
+// JSON, as DB returns them. The caller must hold the lock.
+func (m *Memory) recordEvent(ctx context.Context, todoID int64, action string, changes map[string]Change) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	var decoded map[string]Change
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	m.nextEventID++
+	m.events = append(m.events, Event{
+		ID: m.nextEventID,
+
+		TodoID: todoID,
+
+		Actor: auditFrom(ctx).Actor,
+
+		Action: action,
+
+		Changes: decoded,
+
+		CreatedAt: postgresTime(time.Now()),
+	})
+
+	return nil
+}
+
+// ListEvents gets the change history of an item in the scope, oldest first. Items in the trash
+// have a history; items outside the scope or purged from the trash are not found.
+func (m *Memory) ListEvents(_ context.Context, scope Scope, id int64) ([]Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if item, ok := m.items[id]; !ok || !scope.Contains(item) {
+		return nil, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	return m.itemEvents(id), nil
+}
+
+// ListAllEvents gets the change history of an item of any owner or list, oldest first. Items
+// purged from the trash or deleted with their list have a history; items without one are not
+// found.
+func (m *Memory) ListAllEvents(_ context.Context, id int64) ([]Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.itemEvents(id)
+	if len(events) == 0 {
+		return nil, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	return events, nil
+}
+
+// itemEvents returns the events of an item, oldest first. The caller must hold the lock.
+func (m *Memory) itemEvents(id int64) []Event {
+	events := []Event{}
+	for _, event := range m.events {
+		if event.TodoID == id {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// removeItems removes items for good, recording an ActionPurge event for each, and returns how
+// many were removed. The caller must hold the lock.
+func (m *Memory) removeItems(ctx context.Context, ids []int64) (int64, error) {
+	slices.Sort(ids)
+	for _, id := range ids {
+		if err := m.recordEvent(ctx, id, ActionPurge, map[string]Change{}); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, id := range ids {
+		delete(m.items, id)
+		delete(m.deletedWith, id)
+	}
+
+	return int64(len(ids)), nil
+}
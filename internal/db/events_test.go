@@ -0,0 +1,230 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryEvents(t *testing.T) {
+	testEvents(t, db.NewMemory())
+}
+
+func TestPostgresEvents(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testEvents(t, database)
+}
+
+// testEvents checks that item changes are recorded in the item's history with their actor,
+// action and changed fields.
+func testEvents(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	scope := db.Scope{OwnerID: "events-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+	alice := db.WithAudit(ctx, db.Audit{Actor: "alice"})
+
+	item, err := store.InsertItem(alice, db.Item{OwnerID: scope.OwnerID, Task: "file taxes", Status: "TO_BE_STARTED", Tags: []string{"money"}})
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	child, err := store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, ParentID: item.ID, Task: "find receipts", Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	item.Notes = "before April"
+	if item, err = store.UpdateItem(alice, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	item.Status = "IN_PROGRESS"
+	bob := db.WithAudit(ctx, db.Audit{Actor: "bob", Action: db.ActionTransition})
+	if _, err = store.UpdateItem(bob, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	if err = store.DeleteItem(alice, scope, item.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	if _, err = store.RestoreItem(bob, scope, item.ID); err != nil {
+		t.Fatalf("RestoreItem() error = %v", err)
+	}
+
+	// A failed change is not recorded.
+	if _, err = store.InsertItem(alice, db.Item{OwnerID: scope.OwnerID, Task: "file taxes", Status: "TO_BE_STARTED"}); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Fatalf("InsertItem() of a duplicate error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	events, err := store.ListEvents(ctx, scope, item.ID)
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+
+	want := []struct {
+		actor, action string
+		fields        []string
+	}{
+		{"alice", db.ActionCreate, []string{"due_at", "notes", "priority", "status", "tags", "task"}},
+		{"alice", db.ActionUpdate, []string{"notes"}},
+		{"bob", db.ActionTransition, []string{"status"}},
+		{"alice", db.ActionDelete, []string{"deleted_at"}},
+		{"bob", db.ActionRestore, []string{"deleted_at"}},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("ListEvents() = %+v, want %d events", events, len(want))
+	}
+
+	for i, w := range want {
+		event := events[i]
+		fields := make([]string, 0, len(event.Changes))
+		for _, field := range []string{"deleted_at", "due_at", "notes", "priority", "status", "tags", "task"} {
+			if _, ok := event.Changes[field]; ok {
+				fields = append(fields, field)
+			}
+		}
+
+		if event.TodoID != item.ID || event.Actor != w.actor || event.Action != w.action || !reflect.DeepEqual(fields, w.fields) {
+			t.Errorf("event %d = %+v, want %s by %q changing %v", i, event, w.action, w.actor, w.fields)
+		}
+
+		if i > 0 && (event.ID <= events[i-1].ID || event.CreatedAt.Before(events[i-1].CreatedAt)) {
+			t.Errorf("event %d = %+v, want it after %+v", i, event, events[i-1])
+		}
+	}
+
+	if got := events[0].Changes["tags"]; got.Before != nil || !reflect.DeepEqual(got.After, []any{"money"}) {
+		t.Errorf("created tags = %+v, want none before and [money] after", got)
+	}
+	if got := events[1].Changes["notes"]; got.Before != "" || got.After != "before April" {
+		t.Errorf("updated notes = %+v, want \"\" before and \"before April\" after", got)
+	}
+	if got := events[3].Changes["deleted_at"]; got.Before != nil || got.After == nil {
+		t.Errorf("deleted_at on delete = %+v, want a time after", got)
+	}
+	if got := events[4].Changes["deleted_at"]; got.Before != events[3].Changes["deleted_at"].After || got.After != nil {
+		t.Errorf("deleted_at on restore = %+v, want the deletion time before", got)
+	}
+
+	// The subtask was deleted and restored with its parent, without an actor.
+	childEvents, err := store.ListEvents(ctx, scope, child.ID)
+	if err != nil || len(childEvents) != 3 || childEvents[0].Actor != "" || childEvents[1].Action != db.ActionDelete ||
+		childEvents[1].Actor != "alice" || childEvents[2].Action != db.ActionRestore {
+		t.Errorf("ListEvents() of the subtask = %+v, %v, want create, delete and restore", childEvents, err)
+	}
+
+	// Trashed items keep their history.
+	if err = store.DeleteItem(ctx, scope, child.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if childEvents, err = store.ListEvents(ctx, scope, child.ID); err != nil || len(childEvents) != 4 {
+		t.Errorf("ListEvents() of a trashed item = %+v, %v, want 4 events", childEvents, err)
+	}
+
+	if _, err = store.ListEvents(ctx, db.Scope{OwnerID: "someone else"}, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("ListEvents() outside the scope error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestMemoryRemovalEvents(t *testing.T) {
+	testRemovalEvents(t, db.NewMemory())
+}
+
+func TestPostgresRemovalEvents(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testRemovalEvents(t, database)
+}
+
+// testRemovalEvents checks that items purged from the trash or deleted with their list keep their
+// history, ending in a purge event.
+func testRemovalEvents(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	owner := "removal-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	alice := db.WithAudit(ctx, db.Audit{Actor: "alice"})
+
+	insert := func(item db.Item) db.Item {
+		t.Helper()
+
+		item.Status = "TO_BE_STARTED"
+		inserted, err := store.InsertItem(ctx, item)
+		if err != nil {
+			t.Fatalf("InsertItem(%q) error = %v", item.Task, err)
+		}
+
+		return inserted
+	}
+
+	trashed := insert(db.Item{OwnerID: owner, Task: "old chore"})
+	trashedChild := insert(db.Item{OwnerID: owner, ParentID: trashed.ID, Task: "old subtask"})
+	if err := store.DeleteItem(ctx, db.ItemScope(trashed), trashed.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	if _, err := store.PurgeItems(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeItems() error = %v", err)
+	}
+
+	list, err := store.InsertList(ctx, db.List{Name: "Chores"}, owner)
+	if err != nil {
+		t.Fatalf("InsertList() error = %v", err)
+	}
+	listed := insert(db.Item{OwnerID: owner, ListID: list.ID, Task: "shared chore"})
+
+	if err = store.DeleteList(alice, list.ID); err != nil {
+		t.Fatalf("DeleteList() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		id        int64
+		wantActor string
+		want      []string
+	}{
+		{name: "purged", id: trashed.ID, want: []string{db.ActionCreate, db.ActionDelete, db.ActionPurge}},
+		{name: "purged subtask", id: trashedChild.ID, want: []string{db.ActionCreate, db.ActionDelete, db.ActionPurge}},
+		{name: "deleted with list", id: listed.ID, wantActor: "alice", want: []string{db.ActionCreate, db.ActionPurge}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.GetItem(ctx, db.Scope{OwnerID: owner}, tt.id); !errors.Is(err, apierror.ErrNotFound) {
+				t.Errorf("GetItem() of a removed item error = %v, want %v", err, apierror.ErrNotFound)
+			}
+
+			events, err := store.ListAllEvents(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("ListAllEvents() error = %v", err)
+			}
+
+			actions := make([]string, len(events))
+			for i, event := range events {
+				actions[i] = event.Action
+			}
+			if !reflect.DeepEqual(actions, tt.want) {
+				t.Errorf("ListAllEvents() actions = %v, want %v", actions, tt.want)
+			}
+
+			if last := events[len(events)-1]; last.Actor != tt.wantActor || len(last.Changes) != 0 {
+				t.Errorf("purge event = %+v, want no changes by %q", last, tt.wantActor)
+			}
+		})
+	}
+
+	if _, err = store.ListAllEvents(ctx, listed.ID+100); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("ListAllEvents() of an unknown item error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+)
+
+// List is a named list of todos shared by its members.
+type List struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Member is a member of a list with a role such as "viewer", "editor" or "owner".
+type Member struct {
+	ListID   int64  `json:"list_id"`
+	MemberID string `json:"member_id"`
+	Role     string `json:"role"`
+}
+
+// MemberList is a list together with the role of the member it was listed for.
+type MemberList struct {
+	List
+
+	Role string `json:"role"`
+}
+
+// ownerRole is the role of the member that creates a list.
+const ownerRole = "owner"
+
+// InsertList inserts a new list with ownerID as its owner and returns it as stored.
+func (db *DB) InsertList(ctx context.Context, list List, ownerID string) (List, error) {
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `INSERT INTO lists (name) VALUES ($1) RETURNING id, name, created_at`, list.Name).
+			Scan(&list.ID, &list.Name, &list.CreatedAt); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `INSERT INTO list_members (list_id, member_id, role) VALUES ($1, $2, $3)`,
+			list.ID, ownerID, ownerRole)
+
+		return err
+	})
+	if err != nil {
+		return List{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to insert list into database")
+	}
+
+	return list, nil
+}
+
+// GetList gets a list by its ID.
+func (db *DB) GetList(ctx context.Context, id int64) (List, error) {
+	var list List
+	if err := db.conn.QueryRow(ctx, `SELECT id, name, created_at FROM lists WHERE id = $1`, id).
+		Scan(&list.ID, &list.Name, &list.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return List{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+		}
+
+		return List{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return list, nil
+}
+
+// ListLists gets the lists memberID is a member of, with the member's role, ordered by ID.
+func (db *DB) ListLists(ctx context.Context, memberID string) ([]MemberList, error) {
+	rows, err := db.conn.Query(ctx, `SELECT l.id, l.name, l.created_at, m.role
+		FROM lists l JOIN list_members m ON m.list_id = l.id
+		WHERE m.member_id = $1
+		ORDER BY l.id`, memberID)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	lists := []MemberList{}
+
+	var list MemberList
+	if _, err = pgx.ForEachRow(rows, []any{&list.ID, &list.Name, &list.CreatedAt, &list.Role}, func() error {
+		lists = append(lists, list)
+
+		return nil
+	}); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return lists, nil
+}
+
+// DeleteList deletes a list together with its members and todos, and records an ActionPurge
+// event for each todo.
+func (db *DB) DeleteList(ctx context.Context, id int64) error {
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var listID int64
+		if err := tx.QueryRow(ctx, `SELECT id FROM lists WHERE id = $1 FOR UPDATE`, id).Scan(&listID); err != nil {
+			return err
+		}
+
+		// Deleting the todos here rather than through the foreign key returns them for their events.
+		rows, err := tx.Query(ctx, `DELETE FROM todo_items WHERE list_id = $1 RETURNING id`, id)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := deletedIDs(rows)
+		if err != nil {
+			return err
+		}
+		slices.Sort(deleted)
+
+		if err = insertPurgeEvents(ctx, tx, deleted); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM lists WHERE id = $1`, id)
+
+		return err
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+		}
+
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete list from database")
+	}
+
+	return nil
+}
+
+// GetMember gets the membership of memberID in a list.
+func (db *DB) GetMember(ctx context.Context, listID int64, memberID string) (Member, error) {
+	member := Member{ListID: listID, MemberID: memberID}
+	if err := db.conn.QueryRow(ctx, `SELECT role FROM list_members WHERE list_id = $1 AND member_id = $2`,
+		listID, memberID).Scan(&member.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Member{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "member not found")
+		}
+
+		return Member{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return member, nil
+}
+
+// ListMembers gets the members of a list ordered by member ID.
+func (db *DB) ListMembers(ctx context.Context, listID int64) ([]Member, error) {
+	rows, err := db.conn.Query(ctx, `SELECT member_id, role FROM list_members WHERE list_id = $1 ORDER BY member_id`,
+		listID)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	members := []Member{}
+
+	member := Member{ListID: listID}
+	if _, err = pgx.ForEachRow(rows, []any{&member.MemberID, &member.Role}, func() error {
+		members = append(members, member)
+
+		return nil
+	}); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return members, nil
+}
+
+// PutMember adds a member to a list or changes the role of an existing member.
+func (db *DB) PutMember(ctx context.Context, member Member) (Member, error) {
+	query := `INSERT INTO list_members (list_id, member_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (list_id, member_id) DO UPDATE SET role = EXCLUDED.role`
+	if _, err := db.conn.Exec(ctx, query, member.ListID, member.MemberID, member.Role); err != nil {
+		if isForeignKeyViolation(err) {
+			return Member{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+		}
+
+		return Member{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to store list member")
+	}
+
+	return member, nil
+}
+
+// DeleteMember removes a member from a list.
+func (db *DB) DeleteMember(ctx context.Context, listID int64, memberID string) error {
+	tag, err := db.conn.Exec(ctx, `DELETE FROM list_members WHERE list_id = $1 AND member_id = $2`, listID, memberID)
+	if err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete list member")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "member not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryLists(t *testing.T) {
+	testLists(t, db.NewMemory())
+}
+
+func TestPostgresLists(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testLists(t, database)
+}
+
+// testLists checks the list and membership part of the Storer contract.
+// Member IDs and tasks are unique per run so it can be used against a shared database.
+func testLists(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	owner, editor := "owner-"+suffix, "editor-"+suffix
+
+	list, err := store.InsertList(ctx, db.List{Name: "Groceries"}, owner)
+	if err != nil {
+		t.Fatalf("InsertList() error = %v", err)
+	}
+	if list.ID == 0 || list.CreatedAt.IsZero() {
+		t.Fatalf("InsertList() = %+v, want ID and creation time", list)
+	}
+
+	got, err := store.GetList(ctx, list.ID)
+	if err != nil || got != list {
+		t.Errorf("GetList() = %+v, %v, want %+v", got, err, list)
+	}
+
+	member, err := store.GetMember(ctx, list.ID, owner)
+	if err != nil || member.Role != "owner" {
+		t.Errorf("GetMember() of the creator = %+v, %v, want owner", member, err)
+	}
+
+	if _, err = store.PutMember(ctx, db.Member{ListID: list.ID, MemberID: editor, Role: "viewer"}); err != nil {
+		t.Fatalf("PutMember() error = %v", err)
+	}
+	if _, err = store.PutMember(ctx, db.Member{ListID: list.ID, MemberID: editor, Role: "editor"}); err != nil {
+		t.Fatalf("PutMember() of an existing member error = %v", err)
+	}
+
+	members, err := store.ListMembers(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("ListMembers() error = %v", err)
+	}
+	if len(members) != 2 || members[0].MemberID != editor || members[0].Role != "editor" || members[1].MemberID != owner {
+		t.Errorf("ListMembers() = %+v, want editor and owner", members)
+	}
+
+	lists, err := store.ListLists(ctx, editor)
+	if err != nil {
+		t.Fatalf("ListLists() error = %v", err)
+	}
+	if len(lists) != 1 || lists[0].List != list || lists[0].Role != "editor" {
+		t.Errorf("ListLists() = %+v, want %+v as editor", lists, list)
+	}
+
+	if _, err = store.PutMember(ctx, db.Member{ListID: list.ID + 1000, MemberID: editor, Role: "editor"}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("PutMember() of a missing list error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	// A list task may repeat a personal task of the same owner, but not another task of the list.
+	listScope := db.Scope{ListID: list.ID}
+	task := "List task " + suffix
+
+	if _, err = store.InsertItem(ctx, db.Item{OwnerID: owner, Task: task, Status: "TO_BE_STARTED"}); err != nil {
+		t.Fatalf("InsertItem() of a personal task error = %v", err)
+	}
+
+	item, err := store.InsertItem(ctx, db.Item{OwnerID: owner, ListID: list.ID, Task: task, Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() into a list error = %v", err)
+	}
+	if item.ListID != list.ID {
+		t.Errorf("InsertItem() ListID = %d, want %d", item.ListID, list.ID)
+	}
+
+	if _, err = store.InsertItem(ctx, db.Item{OwnerID: editor, ListID: list.ID, Task: task, Status: "TO_BE_STARTED"}); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Errorf("InsertItem() of a duplicate list task error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	if _, err = store.GetItem(ctx, listScope, item.ID); err != nil {
+		t.Errorf("GetItem() in the list error = %v", err)
+	}
+	if _, err = store.GetItem(ctx, db.Scope{OwnerID: owner}, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() of a list item in the personal scope error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	listed, err := store.ListItems(ctx, db.ListOptions{Scope: listScope})
+	if err != nil || len(listed) != 1 || listed[0].ID != item.ID {
+		t.Errorf("ListItems() of the list = %v, %v, want only %v", listed, err, item)
+	}
+
+	if err = store.DeleteMember(ctx, list.ID, editor); err != nil {
+		t.Fatalf("DeleteMember() error = %v", err)
+	}
+	if err = store.DeleteMember(ctx, list.ID, editor); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("DeleteMember() twice error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if err = store.DeleteList(ctx, list.ID); err != nil {
+		t.Fatalf("DeleteList() error = %v", err)
+	}
+	if _, err = store.GetList(ctx, list.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetList() after delete error = %v, want %v", err, apierror.ErrNotFound)
+	}
+	if _, err = store.GetItem(ctx, listScope, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() of a deleted list's item error = %v, want %v", err, apierror.ErrNotFound)
+	}
+	if _, err = store.GetMember(ctx, list.ID, owner); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetMember() of a deleted list error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
@@ -0,0 +1,731 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+)
+
+// Memory is an in-memory Storer for local development and tests.
+// It is safe for concurrent use and mirrors the behavior of DB.
+type Memory struct {
+	mu     sync.RWMutex
+	items  map[int64]Item
+	nextID int64
+
+	// deletedWith maps each item in the trash to the item whose deletion moved it there.
+	deletedWith map[int64]int64
+
+	// events is the change history of every item, oldest first.
+	events      []Event
+	nextEventID int64
+
+	lists      map[int64]List
+	members    map[int64]map[string]string
+	nextListID int64
+}
+
+// Compile time proof.
+var _ Storer = (*Memory)(nil)
+
+// NewMemory creates a new empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		items: make(map[int64]Item),
+
+		deletedWith: make(map[int64]int64),
+
+		lists: make(map[int64]List),
+
+		members: make(map[int64]map[string]string),
+	}
+}
+
+// InsertItem inserts a new item and returns it as stored.
+// It returns apierror.ErrDuplicateTodo if the item's scope already has the task, and
+// apierror.ErrNotFound if its parent is in the trash or outside its scope.
+func (m *Memory) InsertItem(ctx context.Context, item Item) (Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lists[item.ListID]; item.ListID != 0 && !ok {
+		return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+	}
+
+	parent, ok := m.items[item.ParentID]
+	if item.ParentID != 0 && (!ok || parent.DeletedAt != nil || !ItemScope(item).Contains(parent)) {
+		return Item{}, errParentNotFound
+	}
+
+	if m.taskExists(ItemScope(item), item.Task, 0) {
+		return Item{}, apierror.ErrDuplicateTodo
+	}
+
+	if item.Priority == "" {
+		item.Priority = DefaultPriority
+	}
+	item.DueAt = storedTime(item.DueAt)
+	item.Tags = sortedTags(item.Tags)
+	item.OpenSubtasks, item.Completion, item.DeletedAt = 0, nil, nil
+	item.Version = 1
+
+	m.nextID++
+	item.ID = m.nextID
+	item.CreatedAt = postgresTime(time.Now())
+	if err := m.recordEvent(ctx, item.ID, ActionCreate, createChanges(item)); err != nil {
+		return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to insert item")
+	}
+	m.items[item.ID] = item
+
+	return item, nil
+}
+
+// GetAllItems gets the items of every owner outside the trash ordered by ID.
+func (m *Memory) GetAllItems(_ context.Context) ([]Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedItems(false), nil
+}
+
+// sortedItems returns the items in the trash, or the items outside it, ordered by ID.
+// The caller must hold the lock.
+func (m *Memory) sortedItems(trashed bool) []Item {
+	items := make([]Item, 0, len(m.items))
+	for _, item := range m.items {
+		if (item.DeletedAt != nil) == trashed {
+			items = append(items, m.withProgress(item))
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+
+	return items
+}
+
+// CountItems returns the number of items outside the trash in each status. Statuses without
+// items are omitted.
+func (m *Memory) CountItems(_ context.Context) (map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, item := range m.items {
+		if item.DeletedAt == nil {
+			counts[item.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+// GetItem gets a single item in the scope by its ID. Items outside the scope or in the trash
+// are not found.
+func (m *Memory) GetItem(_ context.Context, scope Scope, id int64) (Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[id]
+	if !ok || !scope.Contains(item) || item.DeletedAt != nil {
+		return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	return m.withProgress(item), nil
+}
+
+// UpdateItem overwrites the task, status, due date, priority and notes of an existing item in
+// the scope of item and returns it as stored. Items in the trash are not found. It returns
+// apierror.ErrPreconditionFailed if item.Version is set and the stored item is at another
+// version, and apierror.ErrDuplicateTodo if another item in the scope has the task.
+func (m *Memory) UpdateItem(ctx context.Context, item Item) (Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scope := ItemScope(item)
+
+	stored, ok := m.items[item.ID]
+	if !ok || !scope.Contains(stored) || stored.DeletedAt != nil {
+		return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	if item.Version != 0 && item.Version != stored.Version {
+		return Item{}, apierror.ErrPreconditionFailed
+	}
+
+	if m.taskExists(scope, item.Task, item.ID) {
+		return Item{}, apierror.ErrDuplicateTodo
+	}
+
+	stored.Version++
+	stored.Task = item.Task
+	stored.Status = item.Status
+	stored.DueAt = storedTime(item.DueAt)
+	stored.Tags = sortedTags(item.Tags)
+	stored.Priority = item.Priority
+	stored.Notes = item.Notes
+	if stored.Priority == "" {
+		stored.Priority = DefaultPriority
+	}
+
+	changes, err := updateChanges(m.items[item.ID], stored)
+	if err == nil {
+		err = m.recordEvent(ctx, item.ID, updateAction(ctx), changes)
+	}
+	if err != nil {
+		return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to update item")
+	}
+	m.items[item.ID] = stored
+
+	return m.withProgress(stored), nil
+}
+
+// DeleteItem moves an item in the scope to the trash by its ID, together with its subtasks
+// outside the trash. Items already in the trash are not found. If version is not zero, it
+// returns apierror.ErrPreconditionFailed unless the item is at that version.
+func (m *Memory) DeleteItem(ctx context.Context, scope Scope, id, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[id]
+	if !ok || !scope.Contains(item) || item.DeletedAt != nil {
+		return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found")
+	}
+
+	if version != 0 && version != item.Version {
+		return apierror.ErrPreconditionFailed
+	}
+
+	// The subtasks are marked as deleted with the item, so they are restored with it.
+	deletedAt := postgresTime(time.Now())
+	tree := m.liveTree(id)
+	slices.Sort(tree)
+	for _, treeID := range tree {
+		if err := m.recordEvent(ctx, treeID, ActionDelete, trashChanges(nil, &deletedAt)); err != nil {
+			return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete item")
+		}
+	}
+
+	for _, treeID := range tree {
+		trashed := m.items[treeID]
+		trashed.DeletedAt = &deletedAt
+		trashed.Version++
+		m.items[treeID] = trashed
+		m.deletedWith[treeID] = id
+	}
+
+	return nil
+}
+
+// RestoreItem moves an item in the scope out of the trash, together with the subtasks trashed
+// with it, and returns it as stored. It returns apierror.ErrParentDeleted if the item's parent
+// is in the trash, and apierror.ErrDuplicateTodo if an item outside the trash has its task.
+func (m *Memory) RestoreItem(ctx context.Context, scope Scope, id int64) (Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[id]
+	if !ok || !scope.Contains(item) || item.DeletedAt == nil {
+		return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found in trash")
+	}
+
+	if parent, ok := m.items[item.ParentID]; ok && parent.DeletedAt != nil {
+		return Item{}, apierror.ErrParentDeleted
+	}
+
+	var tree []int64
+	for treeID, withID := range m.deletedWith {
+		if withID == id {
+			tree = append(tree, treeID)
+		}
+	}
+
+	for _, treeID := range tree {
+		if restored := m.items[treeID]; m.taskExists(ItemScope(restored), restored.Task, treeID) {
+			return Item{}, apierror.ErrDuplicateTodo
+		}
+	}
+
+	slices.Sort(tree)
+	for _, treeID := range tree {
+		if err := m.recordEvent(ctx, treeID, ActionRestore, trashChanges(item.DeletedAt, nil)); err != nil {
+			return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to restore item")
+		}
+	}
+
+	for _, treeID := range tree {
+		restored := m.items[treeID]
+		restored.DeletedAt = nil
+		restored.Version++
+		m.items[treeID] = restored
+		delete(m.deletedWith, treeID)
+	}
+
+	return m.withProgress(m.items[id]), nil
+}
+
+// PurgeItems deletes the items that were moved to the trash before the time, of every owner,
+// together with their subtasks, records an ActionPurge event for each, and returns how many
+// were deleted.
+func (m *Memory) PurgeItems(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged []int64
+	for id, item := range m.items {
+		if item.DeletedAt != nil && item.DeletedAt.Before(before) {
+			purged = append(purged, m.tree(id)...)
+		}
+	}
+	slices.Sort(purged)
+
+	count, err := m.removeItems(ctx, slices.Compact(purged))
+	if err != nil {
+		return 0, apierror.Wrap(err, http.StatusInternalServerError, "failed to purge trash")
+	}
+
+	return count, nil
+}
+
+// liveTree returns the ID of an item followed by the IDs of its subtasks at any depth outside
+// the trash. The caller must hold the lock.
+func (m *Memory) liveTree(id int64) []int64 {
+	ids := []int64{id}
+	for childID, item := range m.items {
+		if item.ParentID == id && item.DeletedAt == nil {
+			ids = append(ids, m.liveTree(childID)...)
+		}
+	}
+
+	return ids
+}
+
+// tree returns the ID of an item followed by the IDs of its subtasks at any depth, in the trash
+// or not. The caller must hold the lock.
+func (m *Memory) tree(id int64) []int64 {
+	ids := []int64{id}
+	for childID, item := range m.items {
+		if item.ParentID == id {
+			ids = append(ids, m.tree(childID)...)
+		}
+	}
+
+	return ids
+}
+
+// ListSubtasks gets the subtasks outside the trash of an item in the scope down to depth levels
+// below it, level by level and in ID order within a level. It returns no items if the item is
+// not in the scope.
+func (m *Memory) ListSubtasks(_ context.Context, scope Scope, id int64, depth int) ([]Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subtasks := []Item{}
+	if item, ok := m.items[id]; !ok || !scope.Contains(item) {
+		return subtasks, nil
+	}
+
+	parents := []int64{id}
+	for level := 0; level < depth && len(parents) > 0; level++ {
+		var children []Item
+		for _, item := range m.items {
+			if item.DeletedAt == nil && slices.Contains(parents, item.ParentID) {
+				children = append(children, m.withProgress(item))
+			}
+		}
+
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].ID < children[j].ID
+		})
+
+		parents = parents[:0]
+		for _, child := range children {
+			parents = append(parents, child.ID)
+		}
+		subtasks = append(subtasks, children...)
+	}
+
+	return subtasks, nil
+}
+
+// withProgress returns item with the progress of its direct subtasks, mirroring the subqueries
+// in itemColumns. The caller must hold the lock.
+func (m *Memory) withProgress(item Item) Item {
+	var done, counted int
+	item.OpenSubtasks, item.Completion = 0, nil
+	for _, child := range m.items {
+		if child.ParentID != item.ID || child.DeletedAt != nil || child.Status == statusCancelled {
+			continue
+		}
+
+		counted++
+		if child.Status == statusDone {
+			done++
+		} else {
+			item.OpenSubtasks++
+		}
+	}
+
+	if counted > 0 {
+		completion := 100 * done / counted
+		item.Completion = &completion
+	}
+
+	return item
+}
+
+// ListItems gets a filtered, sorted page of items.
+func (m *Memory) ListItems(_ context.Context, opts ListOptions) ([]Item, error) {
+	compare, ok := itemCompare[opts.Sort]
+	if !ok {
+		return nil, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid sort column")
+	}
+
+	m.mu.RLock()
+	all := m.sortedItems(opts.Trashed)
+	m.mu.RUnlock()
+
+	items := all[:0]
+	for _, item := range all {
+		if listMatches(opts, item) {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+
+		// Items without a due date sort last in either direction, like NULLS LAST.
+		if opts.Sort == SortDueAt && (a.DueAt == nil) != (b.DueAt == nil) {
+			return b.DueAt == nil
+		}
+
+		if opts.Desc {
+			a, b = b, a
+		}
+
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+
+		return a.ID < b.ID
+	})
+
+	return paginate(items, opts.Limit, opts.Offset), nil
+}
+
+// listMatches reports whether item passes the filters of opts.
+func listMatches(opts ListOptions, item Item) bool {
+	switch {
+	case !opts.AllOwners && !opts.Scope.Contains(item):
+		return false
+	case len(opts.Statuses) > 0 && !slices.Contains(opts.Statuses, item.Status):
+		return false
+	case slices.Contains(opts.ExcludeStatuses, item.Status):
+		return false
+	case len(opts.Priorities) > 0 && !slices.Contains(opts.Priorities, item.Priority):
+		return false
+	case !opts.DueBefore.IsZero() && (item.DueAt == nil || !item.DueAt.Before(opts.DueBefore)):
+		return false
+	case !opts.DueAfter.IsZero() && (item.DueAt == nil || item.DueAt.Before(opts.DueAfter)):
+		return false
+	case len(opts.Tags) > 0 && !tagsMatch(item.Tags, opts.Tags, opts.AllTags):
+		return false
+	}
+
+	return true
+}
+
+// tagsMatch reports whether tags contain any of want, or all of them if all is set.
+func tagsMatch(tags, want []string, all bool) bool {
+	for _, tag := range want {
+		if slices.Contains(tags, tag) != all {
+			return !all
+		}
+	}
+
+	return all
+}
+
+// itemCompare compares two items by a ListOptions.Sort column, mirroring sortColumns.
+// Items without a due date compare equal; ListItems sorts them last.
+var itemCompare = map[string]func(a, b Item) int{
+	"":            func(a, b Item) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	SortCreatedAt: func(a, b Item) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	SortTask:      func(a, b Item) int { return strings.Compare(a.Task, b.Task) },
+	SortStatus:    func(a, b Item) int { return strings.Compare(a.Status, b.Status) },
+	SortDueAt: func(a, b Item) int {
+		if a.DueAt == nil || b.DueAt == nil {
+			return 0
+		}
+
+		return a.DueAt.Compare(*b.DueAt)
+	},
+	SortPriority: func(a, b Item) int { return priorityRank[a.Priority] - priorityRank[b.Priority] },
+}
+
+// SearchItems finds items in the scope outside the trash whose task words start with every query term,
+// most relevant first.
+// The score is the share of task words matched, approximating ts_rank.
+func (m *Memory) SearchItems(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	terms := searchTerms(opts.Query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	items, err := m.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, item := range items {
+		if !opts.Scope.Contains(item) {
+			continue
+		}
+
+		if score, ok := matchTerms(searchTerms(item.Task), terms); ok {
+			results = append(results, SearchResult{Item: item, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return paginate(results, opts.Limit, opts.Offset), nil
+}
+
+// matchTerms reports whether every term prefixes one of words, and the share of words matched.
+func matchTerms(words, terms []string) (float64, bool) {
+	matched := make(map[int]bool)
+	for _, term := range terms {
+		found := false
+		for i, word := range words {
+			if strings.HasPrefix(word, term) {
+				matched[i] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return 0, false
+		}
+	}
+
+	return float64(len(matched)) / float64(len(words)), true
+}
+
+// paginate returns the window of s selected by limit and offset like SQL LIMIT and OFFSET.
+// A limit of zero or less returns everything after offset.
+func paginate[T any](s []T, limit, offset int) []T {
+	if offset >= len(s) {
+		return s[:0]
+	}
+
+	s = s[offset:]
+	if limit > 0 && limit < len(s) {
+		s = s[:limit]
+	}
+
+	return s
+}
+
+// storedTime returns a copy of t as Postgres would store it, so callers cannot change stored items.
+func storedTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	stored := postgresTime(*t)
+
+	return &stored
+}
+
+// postgresTime returns t in UTC, truncated to the microsecond precision Postgres stores
+// timestamps with, so both stores return the same times.
+func postgresTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// taskExists reports whether an item in the scope outside the trash other than exceptID has the
+// task, mirroring the unique indexes on todo_items (owner_id, task) and (list_id, task).
+// The caller must hold the lock.
+func (m *Memory) taskExists(scope Scope, task string, exceptID int64) bool {
+	for id, item := range m.items {
+		if id != exceptID && item.DeletedAt == nil && scope.Contains(item) && item.Task == task {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListTags gets the tags of the items in the scope outside the trash with their usage counts,
+// most used first.
+func (m *Memory) ListTags(_ context.Context, scope Scope) ([]TagCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, item := range m.items {
+		if scope.Contains(item) && item.DeletedAt == nil {
+			for _, tag := range item.Tags {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := []TagCount{}
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags, nil
+}
+
+// InsertList inserts a new list with ownerID as its owner and returns it as stored.
+func (m *Memory) InsertList(_ context.Context, list List, ownerID string) (List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextListID++
+	list.ID = m.nextListID
+	list.CreatedAt = postgresTime(time.Now())
+	m.lists[list.ID] = list
+	m.members[list.ID] = map[string]string{ownerID: ownerRole}
+
+	return list, nil
+}
+
+// GetList gets a list by its ID.
+func (m *Memory) GetList(_ context.Context, id int64) (List, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list, ok := m.lists[id]
+	if !ok {
+		return List{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+	}
+
+	return list, nil
+}
+
+// ListLists gets the lists memberID is a member of, with the member's role, ordered by ID.
+func (m *Memory) ListLists(_ context.Context, memberID string) ([]MemberList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	lists := []MemberList{}
+	for id, list := range m.lists {
+		if role, ok := m.members[id][memberID]; ok {
+			lists = append(lists, MemberList{List: list, Role: role})
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].ID < lists[j].ID
+	})
+
+	return lists, nil
+}
+
+// DeleteList deletes a list together with its members and todos, and records an ActionPurge
+// event for each todo.
+func (m *Memory) DeleteList(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lists[id]; !ok {
+		return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+	}
+
+	var deleted []int64
+	for itemID, item := range m.items {
+		if item.ListID == id {
+			deleted = append(deleted, itemID)
+		}
+	}
+
+	if _, err := m.removeItems(ctx, deleted); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete list")
+	}
+
+	delete(m.lists, id)
+	delete(m.members, id)
+
+	return nil
+}
+
+// GetMember gets the membership of memberID in a list.
+func (m *Memory) GetMember(_ context.Context, listID int64, memberID string) (Member, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	role, ok := m.members[listID][memberID]
+	if !ok {
+		return Member{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "member not found")
+	}
+
+	return Member{ListID: listID, MemberID: memberID, Role: role}, nil
+}
+
+// ListMembers gets the members of a list ordered by member ID.
+func (m *Memory) ListMembers(_ context.Context, listID int64) ([]Member, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := []Member{}
+	for memberID, role := range m.members[listID] {
+		members = append(members, Member{ListID: listID, MemberID: memberID, Role: role})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].MemberID < members[j].MemberID
+	})
+
+	return members, nil
+}
+
+// PutMember adds a member to a list or changes the role of an existing member.
+func (m *Memory) PutMember(_ context.Context, member Member) (Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lists[member.ListID]; !ok {
+		return Member{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+	}
+
+	m.members[member.ListID][member.MemberID] = member.Role
+
+	return member, nil
+}
+
+// DeleteMember removes a member from a list.
+func (m *Memory) DeleteMember(_ context.Context, listID int64, memberID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.members[listID][memberID]; !ok {
+		return apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "member not found")
+	}
+
+	delete(m.members[listID], memberID)
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryStorer(t *testing.T) {
+	testStorer(t, db.NewMemory())
+}
+
+func TestMemory_ConcurrentDuplicateInserts(t *testing.T) {
+	testConcurrentDuplicateInserts(t, db.NewMemory())
+}
+
+func TestMemory_ConcurrentInserts(t *testing.T) {
+	store := db.NewMemory()
+	ctx := context.Background()
+
+	const workers = 50
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.InsertItem(ctx, db.Item{Task: fmt.Sprintf("task %d", i)}); err != nil {
+				t.Errorf("InsertItem() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := store.GetAllItems(ctx)
+	if err != nil {
+		t.Fatalf("GetAllItems() error = %v", err)
+	}
+
+	if len(items) != workers {
+		t.Fatalf("GetAllItems() got %d items, want %d", len(items), workers)
+	}
+
+	for i, item := range items {
+		if item.ID != int64(i+1) {
+			t.Errorf("GetAllItems()[%d].ID = %d, want %d", i, item.ID, i+1)
+		}
+	}
+}
+
+func TestMemoryItemDetails(t *testing.T) {
+	testItemDetails(t, db.NewMemory())
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the advisory lock key that serializes concurrent migration runs.
+const migrationLockID = 72_616_001
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		versionStr, name, _ := strings.Cut(base, "_")
+		version, parseErr := strconv.ParseInt(versionStr, 10, 64)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), parseErr)
+		}
+
+		contents, readErr := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), readErr)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration and returns the ones it applied.
+func (db *DB) MigrateUp(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		statuses, err := migrationStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				continue
+			}
+
+			if err = runMigration(ctx, conn, status.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				status.Version, status.Name); err != nil {
+				return err
+			}
+
+			applied = append(applied, status.Migration)
+			db.logger.InfoContext(ctx, "migration applied", "version", status.Version, "name", status.Name)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown rolls back the most recently applied migration.
+// It returns false if no migration was applied.
+func (db *DB) MigrateDown(ctx context.Context) (Migration, bool, error) {
+	var (
+		rolledBack Migration
+		found      bool
+	)
+
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		statuses, err := migrationStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if statuses[i].AppliedAt == nil {
+				continue
+			}
+
+			rolledBack, found = statuses[i].Migration, true
+
+			if err = runMigration(ctx, conn, rolledBack.Down,
+				`DELETE FROM schema_migrations WHERE version = $1 AND name = $2`,
+				rolledBack.Version, rolledBack.Name); err != nil {
+				return err
+			}
+
+			db.logger.InfoContext(ctx, "migration rolled back", "version", rolledBack.Version, "name", rolledBack.Name)
+
+			return nil
+		}
+
+		return nil
+	})
+
+	return rolledBack, found, err
+}
+
+// MigrationStatus reports every embedded migration and when it was applied.
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		statuses, err = migrationStatus(ctx, conn)
+
+		return err
+	})
+
+	return statuses, err
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory lock.
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return apierror.Wrap(err, http.StatusServiceUnavailable, "failed to acquire database connection")
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to acquire migration lock")
+	}
+
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled.
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	if _, err = conn.Exec(ctx, query); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to create schema_migrations table")
+	}
+
+	return fn(conn)
+}
+
+// migrationStatus merges the embedded migrations with the versions recorded in schema_migrations.
+func migrationStatus(ctx context.Context, conn *pgxpool.Conn) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to load migrations")
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query schema_migrations")
+	}
+
+	var (
+		version int64
+		at      time.Time
+	)
+
+	appliedAt := make(map[int64]time.Time)
+	_, err = pgx.ForEachRow(rows, []any{&version, &at}, func() error {
+		appliedAt[version] = at
+
+		return nil
+	})
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to read schema_migrations")
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if applied, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &applied
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// runMigration executes a migration script and records it in one transaction.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, script, record string, version int64, name string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to begin migration transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err = tx.Exec(ctx, script); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, fmt.Sprintf("failed to run migration %d_%s", version, name))
+	}
+
+	if _, err = tx.Exec(ctx, record, version, name); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to record migration")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to commit migration")
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS todo_items (
+    id BIGSERIAL PRIMARY KEY,
+    task TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'TO_BE_STARTED'
+);
+
+-- Tables created by hand before migrations existed may lack the id column.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS id BIGSERIAL PRIMARY KEY;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS todo_items_task_key;
@@ -0,0 +1,2 @@
+-- Fails if todo_items already holds duplicate tasks; remove them before migrating.
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_task_key ON todo_items (task);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS todo_items_search_vector_idx;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,5 @@
+ALTER TABLE todo_items
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', task)) STORED;
+
+CREATE INDEX IF NOT EXISTS todo_items_search_vector_idx ON todo_items USING GIN (search_vector);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todo_items_status_idx;
+DROP INDEX IF EXISTS todo_items_created_at_idx;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS created_at;
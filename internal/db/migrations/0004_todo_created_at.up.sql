@@ -0,0 +1,4 @@
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS todo_items_created_at_idx ON todo_items (created_at, id);
+CREATE INDEX IF NOT EXISTS todo_items_status_idx ON todo_items (status);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS todo_items_owner_created_at_idx;
+DROP INDEX IF EXISTS todo_items_owner_task_key;
+
+-- Fails if different owners have the same task; remove the duplicates before rolling back.
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_task_key ON todo_items (task);
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,8 @@
+-- Existing todos belong to the empty owner, the one used when callers are not identified.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
+
+-- Tasks are unique per owner instead of globally.
+DROP INDEX IF EXISTS todo_items_task_key;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_owner_task_key ON todo_items (owner_id, task);
+
+CREATE INDEX IF NOT EXISTS todo_items_owner_created_at_idx ON todo_items (owner_id, created_at, id);
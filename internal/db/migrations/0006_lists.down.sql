@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS todo_items_list_created_at_idx;
+DROP INDEX IF EXISTS todo_items_list_task_key;
+DROP INDEX IF EXISTS todo_items_owner_task_key;
+
+-- List todos cannot be kept without their lists.
+DELETE FROM todo_items WHERE list_id IS NOT NULL;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS list_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_owner_task_key ON todo_items (owner_id, task);
+
+DROP TABLE IF EXISTS list_members;
+DROP TABLE IF EXISTS lists;
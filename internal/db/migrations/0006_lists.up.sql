@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS list_members (
+    list_id BIGINT NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    member_id TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    PRIMARY KEY (list_id, member_id)
+);
+
+CREATE INDEX IF NOT EXISTS list_members_member_id_idx ON list_members (member_id);
+
+-- Todos without a list are personal todos of their owner.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS list_id BIGINT REFERENCES lists (id) ON DELETE CASCADE;
+
+-- Personal tasks are unique per owner, list tasks per list.
+DROP INDEX IF EXISTS todo_items_owner_task_key;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_owner_task_key ON todo_items (owner_id, task) WHERE list_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_list_task_key ON todo_items (list_id, task) WHERE list_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS todo_items_list_created_at_idx ON todo_items (list_id, created_at, id) WHERE list_id IS NOT NULL;
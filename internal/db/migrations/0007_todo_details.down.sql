@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_items_due_at_idx;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS notes;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS priority;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS due_at;
@@ -0,0 +1,7 @@
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ;
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'normal'
+    CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+
+-- Serves the overdue and due date filters.
+CREATE INDEX IF NOT EXISTS todo_items_due_at_idx ON todo_items (due_at) WHERE due_at IS NOT NULL;
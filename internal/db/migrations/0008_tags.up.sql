@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+    todo_id BIGINT NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+-- Serves the tag filters and usage counts.
+CREATE INDEX IF NOT EXISTS todo_tags_tag_id_idx ON todo_tags (tag_id);
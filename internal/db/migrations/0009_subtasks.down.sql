@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS todo_items_parent_id_idx;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS parent_id;
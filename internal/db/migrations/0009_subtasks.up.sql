@@ -0,0 +1,6 @@
+-- Subtasks belong to a parent todo in the same scope and are deleted with it.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS parent_id BIGINT
+    CONSTRAINT todo_items_parent_id_fkey REFERENCES todo_items (id) ON DELETE CASCADE;
+
+-- Serves the subtask lookups and the completion of parents.
+CREATE INDEX IF NOT EXISTS todo_items_parent_id_idx ON todo_items (parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS todo_items_deleted_with_idx;
+DROP INDEX IF EXISTS todo_items_deleted_at_idx;
+DROP INDEX IF EXISTS todo_items_list_task_key;
+DROP INDEX IF EXISTS todo_items_owner_task_key;
+
+-- Trashed todos may repeat live tasks, so they are purged before the indexes are restored.
+DELETE FROM todo_items WHERE deleted_at IS NOT NULL;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS deleted_with;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS deleted_at;
+
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_owner_task_key ON todo_items (owner_id, task) WHERE list_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_list_task_key ON todo_items (list_id, task) WHERE list_id IS NOT NULL;
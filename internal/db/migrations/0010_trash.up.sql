@@ -0,0 +1,16 @@
+-- Deleted todos stay in the trash until they are restored or purged. deleted_with is the todo
+-- whose deletion moved them there, so restoring it brings back the subtasks deleted with it.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS deleted_with BIGINT;
+
+-- Trashed todos do not keep their task from being used again.
+DROP INDEX IF EXISTS todo_items_owner_task_key;
+DROP INDEX IF EXISTS todo_items_list_task_key;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_owner_task_key ON todo_items (owner_id, task)
+    WHERE list_id IS NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_list_task_key ON todo_items (list_id, task)
+    WHERE list_id IS NOT NULL AND deleted_at IS NULL;
+
+-- Serve the purge of old trash and restoring.
+CREATE INDEX IF NOT EXISTS todo_items_deleted_at_idx ON todo_items (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS todo_items_deleted_with_idx ON todo_items (deleted_with) WHERE deleted_with IS NOT NULL;
@@ -0,0 +1,13 @@
+-- The audit log of changes to todos. Events are kept when their todo is purged from the trash,
+-- so todo_id does not reference todo_items.
+CREATE TABLE IF NOT EXISTS todo_events (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id BIGINT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Serves the history of a todo in order.
+CREATE INDEX IF NOT EXISTS todo_events_todo_id_idx ON todo_events (todo_id, id);
@@ -0,0 +1,2 @@
+-- Counts the changes to a todo, for optimistic concurrency control.
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
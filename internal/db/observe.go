@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Storer operation names passed to an Observer.
+const (
+	OpInsertItem    = "insert_item"
+	OpGetAllItems   = "get_all_items"
+	OpGetItem       = "get_item"
+	OpUpdateItem    = "update_item"
+	OpDeleteItem    = "delete_item"
+	OpRestoreItem   = "restore_item"
+	OpPurgeItems    = "purge_items"
+	OpSearchItems   = "search_items"
+	OpListItems     = "list_items"
+	OpCountItems    = "count_items"
+	OpListTags      = "list_tags"
+	OpListSubtasks  = "list_subtasks"
+	OpListEvents    = "list_events"
+	OpListAllEvents = "list_all_events"
+	OpInsertItems   = "insert_items"
+	OpInTx          = "in_tx"
+
+	OpInsertList   = "insert_list"
+	OpGetList      = "get_list"
+	OpListLists    = "list_lists"
+	OpDeleteList   = "delete_list"
+	OpGetMember    = "get_member"
+	OpListMembers  = "list_members"
+	OpPutMember    = "put_member"
+	OpDeleteMember = "delete_member"
+)
+
+// Observer is called when a Storer operation starts. It may return a derived context for
+// the operation, and a function that is called with the operation's error once it ends.
+type Observer func(ctx context.Context, op string) (context.Context, func(err error))
+
+// observed is a Storer that reports every operation to its observers.
+type observed struct {
+	store     Storer
+	observers []Observer
+}
+
+// Compile time proof.
+var _ Storer = (*observed)(nil)
+
+// Observe wraps store so that every operation is reported to the observers, for example
+// to record metrics. Observers are started in order and finished in reverse order.
+func Observe(store Storer, observers ...Observer) Storer {
+	if len(observers) == 0 {
+		return store
+	}
+
+	return &observed{store: store, observers: observers}
+}
+
+// start starts the observers for an operation and returns the operation context and a
+// function that finishes them.
+func (o *observed) start(ctx context.Context, op string) (context.Context, func(error)) {
+	done := make([]func(error), len(o.observers))
+	for i, observer := range o.observers {
+		ctx, done[i] = observer(ctx, op)
+	}
+
+	return ctx, func(err error) {
+		for i := len(done) - 1; i >= 0; i-- {
+			done[i](err)
+		}
+	}
+}
+
+// InsertItem implements Storer.
+func (o *observed) InsertItem(ctx context.Context, item Item) (Item, error) {
+	ctx, done := o.start(ctx, OpInsertItem)
+	item, err := o.store.InsertItem(ctx, item)
+	done(err)
+
+	return item, err
+}
+
+// GetAllItems implements Storer.
+func (o *observed) GetAllItems(ctx context.Context) ([]Item, error) {
+	ctx, done := o.start(ctx, OpGetAllItems)
+	items, err := o.store.GetAllItems(ctx)
+	done(err)
+
+	return items, err
+}
+
+// GetItem implements Storer.
+func (o *observed) GetItem(ctx context.Context, scope Scope, id int64) (Item, error) {
+	ctx, done := o.start(ctx, OpGetItem)
+	item, err := o.store.GetItem(ctx, scope, id)
+	done(err)
+
+	return item, err
+}
+
+// UpdateItem implements Storer.
+func (o *observed) UpdateItem(ctx context.Context, item Item) (Item, error) {
+	ctx, done := o.start(ctx, OpUpdateItem)
+	item, err := o.store.UpdateItem(ctx, item)
+	done(err)
+
+	return item, err
+}
+
+// DeleteItem implements Storer.
+func (o *observed) DeleteItem(ctx context.Context, scope Scope, id, version int64) error {
+	ctx, done := o.start(ctx, OpDeleteItem)
+	err := o.store.DeleteItem(ctx, scope, id, version)
+	done(err)
+
+	return err
+}
+
+// RestoreItem implements Storer.
+func (o *observed) RestoreItem(ctx context.Context, scope Scope, id int64) (Item, error) {
+	ctx, done := o.start(ctx, OpRestoreItem)
+	item, err := o.store.RestoreItem(ctx, scope, id)
+	done(err)
+
+	return item, err
+}
+
+// PurgeItems implements Storer.
+func (o *observed) PurgeItems(ctx context.Context, before time.Time) (int64, error) {
+	ctx, done := o.start(ctx, OpPurgeItems)
+	purged, err := o.store.PurgeItems(ctx, before)
+	done(err)
+
+	return purged, err
+}
+
+// SearchItems implements Storer.
+func (o *observed) SearchItems(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	ctx, done := o.start(ctx, OpSearchItems)
+	results, err := o.store.SearchItems(ctx, opts)
+	done(err)
+
+	return results, err
+}
+
+// ListItems implements Storer.
+func (o *observed) ListItems(ctx context.Context, opts ListOptions) ([]Item, error) {
+	ctx, done := o.start(ctx, OpListItems)
+	items, err := o.store.ListItems(ctx, opts)
+	done(err)
+
+	return items, err
+}
+
+// CountItems implements Storer.
+func (o *observed) CountItems(ctx context.Context) (map[string]int64, error) {
+	ctx, done := o.start(ctx, OpCountItems)
+	counts, err := o.store.CountItems(ctx)
+	done(err)
+
+	return counts, err
+}
+
+// ListTags implements Storer.
+func (o *observed) ListTags(ctx context.Context, scope Scope) ([]TagCount, error) {
+	ctx, done := o.start(ctx, OpListTags)
+	tags, err := o.store.ListTags(ctx, scope)
+	done(err)
+
+	return tags, err
+}
+
+// ListSubtasks implements Storer.
+func (o *observed) ListSubtasks(ctx context.Context, scope Scope, id int64, depth int) ([]Item, error) {
+	ctx, done := o.start(ctx, OpListSubtasks)
+	items, err := o.store.ListSubtasks(ctx, scope, id, depth)
+	done(err)
+
+	return items, err
+}
+
+// ListEvents implements Storer.
+func (o *observed) ListEvents(ctx context.Context, scope Scope, id int64) ([]Event, error) {
+	ctx, done := o.start(ctx, OpListEvents)
+	events, err := o.store.ListEvents(ctx, scope, id)
+	done(err)
+
+	return events, err
+}
+
+// ListAllEvents implements Storer.
+func (o *observed) ListAllEvents(ctx context.Context, id int64) ([]Event, error) {
+	ctx, done := o.start(ctx, OpListAllEvents)
+	events, err := o.store.ListAllEvents(ctx, id)
+	done(err)
+
+	return events, err
+}
+
+// InsertItems implements Storer.
+func (o *observed) InsertItems(ctx context.Context, items []Item) ([]Item, error) {
+	ctx, done := o.start(ctx, OpInsertItems)
+	items, err := o.store.InsertItems(ctx, items)
+	done(err)
+
+	return items, err
+}
+
+// InTx implements Storer. The operations of the transaction are observed as well.
+func (o *observed) InTx(ctx context.Context, fn func(tx Storer) error) error {
+	ctx, done := o.start(ctx, OpInTx)
+	err := o.store.InTx(ctx, func(tx Storer) error {
+		return fn(&observed{store: tx, observers: o.observers})
+	})
+	done(err)
+
+	return err
+}
+
+// InsertList implements Storer.
+func (o *observed) InsertList(ctx context.Context, list List, ownerID string) (List, error) {
+	ctx, done := o.start(ctx, OpInsertList)
+	list, err := o.store.InsertList(ctx, list, ownerID)
+	done(err)
+
+	return list, err
+}
+
+// GetList implements Storer.
+func (o *observed) GetList(ctx context.Context, id int64) (List, error) {
+	ctx, done := o.start(ctx, OpGetList)
+	list, err := o.store.GetList(ctx, id)
+	done(err)
+
+	return list, err
+}
+
+// ListLists implements Storer.
+func (o *observed) ListLists(ctx context.Context, memberID string) ([]MemberList, error) {
+	ctx, done := o.start(ctx, OpListLists)
+	lists, err := o.store.ListLists(ctx, memberID)
+	done(err)
+
+	return lists, err
+}
+
+// DeleteList implements Storer.
+func (o *observed) DeleteList(ctx context.Context, id int64) error {
+	ctx, done := o.start(ctx, OpDeleteList)
+	err := o.store.DeleteList(ctx, id)
+	done(err)
+
+	return err
+}
+
+// GetMember implements Storer.
+func (o *observed) GetMember(ctx context.Context, listID int64, memberID string) (Member, error) {
+	ctx, done := o.start(ctx, OpGetMember)
+	member, err := o.store.GetMember(ctx, listID, memberID)
+	done(err)
+
+	return member, err
+}
+
+// ListMembers implements Storer.
+func (o *observed) ListMembers(ctx context.Context, listID int64) ([]Member, error) {
+	ctx, done := o.start(ctx, OpListMembers)
+	members, err := o.store.ListMembers(ctx, listID)
+	done(err)
+
+	return members, err
+}
+
+// PutMember implements Storer.
+func (o *observed) PutMember(ctx context.Context, member Member) (Member, error) {
+	ctx, done := o.start(ctx, OpPutMember)
+	member, err := o.store.PutMember(ctx, member)
+	done(err)
+
+	return member, err
+}
+
+// DeleteMember implements Storer.
+func (o *observed) DeleteMember(ctx context.Context, listID int64, memberID string) error {
+	ctx, done := o.start(ctx, OpDeleteMember)
+	err := o.store.DeleteMember(ctx, listID, memberID)
+	done(err)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+// ctxKey is a context key used to check that observer contexts reach the store.
+type ctxKey struct{}
+
+// recordingStore is a Storer that records the context value seen by GetItem.
+type recordingStore struct {
+	db.Storer
+	seen any
+}
+
+func (r *recordingStore) GetItem(ctx context.Context, scope db.Scope, id int64) (db.Item, error) {
+	r.seen = ctx.Value(ctxKey{})
+
+	return r.Storer.GetItem(ctx, scope, id)
+}
+
+func TestObserve(t *testing.T) {
+	var events []string
+	observer := func(name string) db.Observer {
+		return func(ctx context.Context, op string) (context.Context, func(error)) {
+			events = append(events, name+" start "+op)
+
+			return context.WithValue(ctx, ctxKey{}, name), func(err error) {
+				result := "ok"
+				if err != nil {
+					result = "error"
+				}
+				events = append(events, name+" done "+op+" "+result)
+			}
+		}
+	}
+
+	inner := &recordingStore{Storer: db.NewMemory()}
+	store := db.Observe(inner, observer("outer"), observer("inner"))
+
+	item, err := store.InsertItem(context.Background(), db.Item{Task: "todo1", Status: "TO_BE_STARTED"})
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if _, err = store.GetItem(context.Background(), db.Scope{}, item.ID+1); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	want := []string{
+		"outer start insert_item", "inner start insert_item", "inner done insert_item ok", "outer done insert_item ok",
+		"outer start get_item", "inner start get_item", "inner done get_item error", "outer done get_item error",
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	if inner.seen != "inner" {
+		t.Errorf("store saw context value %v, want the innermost observer's context", inner.seen)
+	}
+}
+
+func TestObserve_NoObservers(t *testing.T) {
+	store := db.NewMemory()
+
+	if got := db.Observe(store); got != store {
+		t.Errorf("Observe() = %v, want the store itself", got)
+	}
+}
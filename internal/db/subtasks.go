@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+)
+
+// ListSubtasks gets the subtasks outside the trash of an item in the scope down to depth levels
+// below it, level by level and in ID order within a level. It returns no items if the item is
+// not in the scope.
+func (db *DB) ListSubtasks(ctx context.Context, scope Scope, id int64, depth int) ([]Item, error) {
+	condition, arg := scope.condition(3)
+
+	// Subtasks share the scope of their parent, so only the first level needs the condition.
+	// Parents never change, so the tree has no cycles; depth bounds the recursion regardless.
+	query := `WITH RECURSIVE tree (id, depth) AS (
+			SELECT id, 1 FROM todo_items WHERE parent_id = $1 AND deleted_at IS NULL AND ` + condition + `
+			UNION ALL
+			SELECT s.id, tree.depth + 1 FROM todo_items s JOIN tree ON s.parent_id = tree.id
+			WHERE tree.depth < $2 AND s.deleted_at IS NULL
+		)
+		SELECT ` + itemColumns + ` FROM todo_items JOIN tree USING (id)
+		ORDER BY tree.depth, id`
+
+	return db.queryItems(ctx, query, id, depth, arg)
+}
@@ -0,0 +1,127 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemorySubtasks(t *testing.T) {
+	testSubtasks(t, db.NewMemory())
+}
+
+func TestPostgresSubtasks(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testSubtasks(t, database)
+}
+
+// testSubtasks checks that subtasks are stored under their parent, listed level by level,
+// counted towards the progress of their parent and deleted with it.
+func testSubtasks(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	scope := db.Scope{OwnerID: "subtasks-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+
+	insert := func(task string, parentID int64, status string) db.Item {
+		t.Helper()
+
+		item, err := store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, ParentID: parentID, Task: task, Status: status})
+		if err != nil {
+			t.Fatalf("InsertItem(%q) error = %v", task, err)
+		}
+
+		return item
+	}
+
+	trip := insert("plan trip", 0, "IN_PROGRESS")
+	tickets := insert("book tickets", trip.ID, "DONE")
+	packing := insert("pack", trip.ID, "TO_BE_STARTED")
+	visa := insert("visa", trip.ID, "CANCELLED")
+	clothes := insert("pack clothes", packing.ID, "TO_BE_STARTED")
+	charger := insert("pack charger", packing.ID, "DONE")
+
+	if packing.ParentID != trip.ID {
+		t.Errorf("InsertItem() parent = %d, want %d", packing.ParentID, trip.ID)
+	}
+
+	if _, err := store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, ParentID: charger.ID + 100, Task: "orphan", Status: "TO_BE_STARTED"}); err == nil {
+		t.Error("InsertItem() with an unknown parent error = nil, want an error")
+	}
+
+	if _, err := store.InsertItem(ctx, db.Item{OwnerID: "someone-else", ParentID: trip.ID, Task: "stowaway", Status: "TO_BE_STARTED"}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("InsertItem() under another owner's parent error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	got, err := store.GetItem(ctx, scope, trip.ID)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if got.OpenSubtasks != 1 || got.Completion == nil || *got.Completion != 50 {
+		t.Errorf("GetItem() progress = %d open, %v%%, want 1 open, 50%%", got.OpenSubtasks, got.Completion)
+	}
+	if tickets.Completion != nil {
+		t.Errorf("InsertItem() completion = %v, want nil without subtasks", *tickets.Completion)
+	}
+
+	tests := []struct {
+		name  string
+		scope db.Scope
+		id    int64
+		depth int
+		want  []int64
+	}{
+		{name: "children", scope: scope, id: trip.ID, depth: 1, want: []int64{tickets.ID, packing.ID, visa.ID}},
+		{name: "tree", scope: scope, id: trip.ID, depth: 2, want: []int64{tickets.ID, packing.ID, visa.ID, clothes.ID, charger.ID}},
+		{name: "leaf", scope: scope, id: charger.ID, depth: 3, want: []int64{}},
+		{name: "other scope", scope: db.Scope{OwnerID: "someone-else"}, id: trip.ID, depth: 2, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := store.ListSubtasks(ctx, tt.scope, tt.id, tt.depth)
+			if err != nil {
+				t.Fatalf("ListSubtasks() error = %v", err)
+			}
+
+			ids := []int64{}
+			for _, item := range items {
+				ids = append(ids, item.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ListSubtasks() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	clothes.Status = "DONE"
+	if _, err = store.UpdateItem(ctx, clothes); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	updated, err := store.UpdateItem(ctx, packing)
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if updated.OpenSubtasks != 0 || updated.Completion == nil || *updated.Completion != 100 {
+		t.Errorf("UpdateItem() progress = %d open, %v%%, want 0 open, 100%%", updated.OpenSubtasks, updated.Completion)
+	}
+
+	if err = store.DeleteItem(ctx, scope, trip.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if _, err = store.GetItem(ctx, scope, charger.ID); err == nil {
+		t.Error("GetItem() of a subtask of a deleted item error = nil, want not found")
+	}
+	if _, err = store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, ParentID: trip.ID, Task: "late", Status: "TO_BE_STARTED"}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("InsertItem() under a deleted parent error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
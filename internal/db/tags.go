@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+)
+
+// TagCount is a tag with the number of items that carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// ListTags gets the tags of the items in the scope outside the trash with their usage counts,
+// most used first.
+func (db *DB) ListTags(ctx context.Context, scope Scope) ([]TagCount, error) {
+	condition, arg := scope.condition(1)
+	rows, err := db.conn.Query(ctx, `SELECT t.name, count(*)
+		FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		JOIN todo_items ON todo_items.id = tt.todo_id
+		WHERE todo_items.deleted_at IS NULL AND `+condition+`
+		GROUP BY t.name
+		ORDER BY count(*) DESC, t.name`, arg)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	tags := []TagCount{}
+
+	var tag TagCount
+	if _, err = pgx.ForEachRow(rows, []any{&tag.Tag, &tag.Count}, func() error {
+		tags = append(tags, tag)
+
+		return nil
+	}); err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to query database")
+	}
+
+	return tags, nil
+}
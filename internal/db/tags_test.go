@@ -0,0 +1,127 @@
+package db_test
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryTags(t *testing.T) {
+	testTags(t, db.NewMemory())
+}
+
+func TestPostgresTags(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testTags(t, database)
+}
+
+// testTags checks that tags are stored, replaced, filtered on and counted per scope.
+func testTags(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	scope := db.Scope{OwnerID: "tags-" + suffix}
+
+	seed := []db.Item{
+		{Task: "groceries", Tags: []string{"shopping", "home"}},
+		{Task: "hardware", Tags: []string{"shopping"}},
+		{Task: "laundry", Tags: []string{"home"}},
+		{Task: "untagged"},
+	}
+
+	inserted := make([]db.Item, 0, len(seed))
+	for _, item := range seed {
+		item.OwnerID = scope.OwnerID
+		item.Status = "TO_BE_STARTED"
+
+		stored, err := store.InsertItem(ctx, item)
+		if err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+		inserted = append(inserted, stored)
+	}
+
+	if got := inserted[0].Tags; !slices.Equal(got, []string{"home", "shopping"}) {
+		t.Errorf("InsertItem() tags = %v, want them sorted", got)
+	}
+	if inserted[3].Tags == nil {
+		t.Error("InsertItem() tags = nil, want an empty slice")
+	}
+
+	// Another owner's tags are not counted.
+	if _, err := store.InsertItem(ctx, db.Item{OwnerID: "other-" + suffix, Task: "other", Tags: []string{"home"}}); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		all  bool
+		want []string
+	}{
+		{name: "any", tags: []string{"home", "shopping"}, want: []string{"groceries", "hardware", "laundry"}},
+		{name: "all", tags: []string{"home", "shopping"}, all: true, want: []string{"groceries"}},
+		{name: "unknown tag", tags: []string{"garden"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := store.ListItems(ctx, db.ListOptions{Scope: scope, Tags: tt.tags, AllTags: tt.all, Sort: db.SortTask})
+			if err != nil {
+				t.Fatalf("ListItems() error = %v", err)
+			}
+
+			var tasks []string
+			for _, item := range items {
+				tasks = append(tasks, item.Task)
+			}
+			if !slices.Equal(tasks, tt.want) {
+				t.Errorf("ListItems() = %v, want %v", tasks, tt.want)
+			}
+		})
+	}
+
+	counts, err := store.ListTags(ctx, scope)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	want := []db.TagCount{{Tag: "home", Count: 2}, {Tag: "shopping", Count: 2}}
+	if !slices.Equal(counts, want) {
+		t.Errorf("ListTags() = %v, want %v", counts, want)
+	}
+
+	laundry := inserted[2]
+	laundry.Tags = []string{"weekly"}
+	updated, err := store.UpdateItem(ctx, laundry)
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if !slices.Equal(updated.Tags, []string{"weekly"}) {
+		t.Errorf("UpdateItem() tags = %v, want [weekly]", updated.Tags)
+	}
+
+	got, err := store.GetItem(ctx, scope, laundry.ID)
+	if err != nil || !slices.Equal(got.Tags, []string{"weekly"}) {
+		t.Errorf("GetItem() tags = %v, %v, want [weekly]", got.Tags, err)
+	}
+
+	if err = store.DeleteItem(ctx, scope, inserted[0].ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	counts, err = store.ListTags(ctx, scope)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	want = []db.TagCount{{Tag: "shopping", Count: 1}, {Tag: "weekly", Count: 1}}
+	if !slices.Equal(counts, want) {
+		t.Errorf("ListTags() after changes = %v, want %v", counts, want)
+	}
+}
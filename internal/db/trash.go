@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5"
+)
+
+// RestoreItem moves an item in the scope out of the trash, together with the subtasks trashed
+// with it, and returns it as stored. It returns apierror.ErrParentDeleted if the item's parent
+// is in the trash, and apierror.ErrDuplicateTodo if an item outside the trash has its task.
+func (db *DB) RestoreItem(ctx context.Context, scope Scope, id int64) (Item, error) {
+	condition, arg := scope.condition(2)
+
+	var item Item
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var parentDeleted bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (
+				SELECT 1 FROM todo_items p WHERE p.id = todo_items.parent_id AND p.deleted_at IS NOT NULL
+			)
+			FROM todo_items WHERE id = $1 AND deleted_at IS NOT NULL AND `+condition+`
+			FOR UPDATE`, id, arg).Scan(&parentDeleted); err != nil {
+			return err
+		}
+
+		if parentDeleted {
+			return apierror.ErrParentDeleted
+		}
+
+		// The self-join reads the rows as they were before the update.
+		rows, err := tx.Query(ctx, `UPDATE todo_items t SET deleted_at = NULL, deleted_with = NULL, version = t.version + 1
+			FROM todo_items o WHERE o.id = t.id AND t.deleted_with = $1
+			RETURNING t.id, o.deleted_at`, id)
+		if err != nil {
+			return err
+		}
+
+		// The subtasks restored with the item were deleted with it, at the same time.
+		var restored []int64
+		var treeID int64
+		var deletedAt time.Time
+		if _, err = pgx.ForEachRow(rows, []any{&treeID, &deletedAt}, func() error {
+			restored = append(restored, treeID)
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		slices.Sort(restored)
+		for _, treeID := range restored {
+			if err = insertEvent(ctx, tx, treeID, ActionRestore, trashChanges(&deletedAt, nil)); err != nil {
+				return err
+			}
+		}
+
+		return tx.QueryRow(ctx, `SELECT `+itemColumns+` FROM todo_items WHERE id = $1`, id).Scan(itemFields(&item)...)
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Item{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "todo item not found in trash")
+		}
+
+		if errors.Is(err, apierror.ErrParentDeleted) {
+			return Item{}, err
+		}
+
+		if isUniqueViolation(err) {
+			return Item{}, apierror.ErrDuplicateTodo
+		}
+
+		return Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to restore item in database")
+	}
+
+	return item, nil
+}
+
+// PurgeItems deletes the items that were moved to the trash before the time, of every owner,
+// together with their subtasks, records an ActionPurge event for each, and returns how many
+// were deleted.
+func (db *DB) PurgeItems(ctx context.Context, before time.Time) (int64, error) {
+	var purged []int64
+	if err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		// The subtasks would go with their parent through the foreign key anyway; naming them
+		// here records their event too.
+		rows, err := tx.Query(ctx, `WITH RECURSIVE tree AS (
+				SELECT id FROM todo_items WHERE deleted_at < $1
+				UNION
+				SELECT s.id FROM todo_items s JOIN tree ON s.parent_id = tree.id
+			)
+			DELETE FROM todo_items WHERE id IN (SELECT id FROM tree) RETURNING id`, before)
+		if err != nil {
+			return err
+		}
+
+		if purged, err = deletedIDs(rows); err != nil {
+			return err
+		}
+		slices.Sort(purged)
+
+		return insertPurgeEvents(ctx, tx, purged)
+	}); err != nil {
+		return 0, apierror.Wrap(err, http.StatusInternalServerError, "failed to purge trash")
+	}
+
+	return int64(len(purged)), nil
+}
@@ -0,0 +1,140 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryTrash(t *testing.T) {
+	testTrash(t, db.NewMemory())
+}
+
+func TestPostgresTrash(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testTrash(t, database)
+}
+
+// testTrash checks that deleted items and their subtasks move to the trash, are hidden from
+// other reads, can be restored and are purged once old enough.
+func testTrash(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	scope := db.Scope{OwnerID: "trash-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+
+	insert := func(task string, parentID int64) db.Item {
+		t.Helper()
+
+		item, err := store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, ParentID: parentID, Task: task, Status: "TO_BE_STARTED", Tags: []string{"trash"}})
+		if err != nil {
+			t.Fatalf("InsertItem(%q) error = %v", task, err)
+		}
+
+		return item
+	}
+
+	move := insert("move house", 0)
+	boxes := insert("buy boxes", move.ID)
+	tape := insert("buy tape", move.ID)
+	keep := insert("keep me", 0)
+
+	// A subtask trashed on its own stays in the trash when its parent is restored.
+	if err := store.DeleteItem(ctx, scope, tape.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if err := store.DeleteItem(ctx, scope, move.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if err := store.DeleteItem(ctx, scope, move.ID, 0); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("DeleteItem() of a trashed item error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err := store.GetItem(ctx, scope, boxes.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() of a trashed subtask error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	live, err := store.ListItems(ctx, db.ListOptions{Scope: scope})
+	if err != nil || len(live) != 1 || live[0].ID != keep.ID {
+		t.Errorf("ListItems() = %v, %v, want only %d", live, err, keep.ID)
+	}
+
+	if results, searchErr := store.SearchItems(ctx, db.SearchOptions{Scope: scope, Query: "buy"}); searchErr != nil || len(results) != 0 {
+		t.Errorf("SearchItems() = %v, %v, want no trashed items", results, searchErr)
+	}
+
+	if tags, tagsErr := store.ListTags(ctx, scope); tagsErr != nil || len(tags) != 1 || tags[0].Count != 1 {
+		t.Errorf("ListTags() = %v, %v, want trash counted once", tags, tagsErr)
+	}
+
+	trashed, err := store.ListItems(ctx, db.ListOptions{Scope: scope, Trashed: true})
+	if err != nil || len(trashed) != 3 {
+		t.Fatalf("ListItems() of the trash = %v, %v, want 3 items", trashed, err)
+	}
+	for _, item := range trashed {
+		if item.DeletedAt == nil {
+			t.Errorf("ListItems() of the trash item %d has no deletion time", item.ID)
+		}
+	}
+
+	// The task of a trashed item may be used again, which blocks restoring it.
+	again := insert("keep again", 0)
+	if err = store.DeleteItem(ctx, scope, again.ID, 0); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	insert("keep again", 0)
+	if _, err = store.RestoreItem(ctx, scope, again.ID); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Errorf("RestoreItem() of a reused task error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	if _, err = store.RestoreItem(ctx, scope, boxes.ID); !errors.Is(err, apierror.ErrParentDeleted) {
+		t.Errorf("RestoreItem() under a trashed parent error = %v, want %v", err, apierror.ErrParentDeleted)
+	}
+
+	if _, err = store.RestoreItem(ctx, db.Scope{OwnerID: "someone-else"}, move.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("RestoreItem() in another scope error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	restored, err := store.RestoreItem(ctx, scope, move.ID)
+	if err != nil {
+		t.Fatalf("RestoreItem() error = %v", err)
+	}
+	if restored.DeletedAt != nil || restored.OpenSubtasks != 1 {
+		t.Errorf("RestoreItem() = %+v, want it outside the trash with one open subtask", restored)
+	}
+
+	if _, err = store.GetItem(ctx, scope, boxes.ID); err != nil {
+		t.Errorf("GetItem() of a restored subtask error = %v", err)
+	}
+	if _, err = store.GetItem(ctx, scope, tape.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("GetItem() of a subtask trashed on its own error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err = store.RestoreItem(ctx, scope, keep.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("RestoreItem() of an item outside the trash error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err = store.PurgeItems(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("PurgeItems() error = %v", err)
+	}
+	if trashed, err = store.ListItems(ctx, db.ListOptions{Scope: scope, Trashed: true}); err != nil || len(trashed) != 2 {
+		t.Errorf("ListItems() of the trash after purging old items = %v, %v, want 2 items", trashed, err)
+	}
+
+	if _, err = store.PurgeItems(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeItems() error = %v", err)
+	}
+	if trashed, err = store.ListItems(ctx, db.ListOptions{Scope: scope, Trashed: true}); err != nil || len(trashed) != 0 {
+		t.Errorf("ListItems() of the trash after purging = %v, %v, want none", trashed, err)
+	}
+	if _, err = store.RestoreItem(ctx, scope, tape.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("RestoreItem() of a purged item error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
@@ -0,0 +1,72 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestMemoryVersions(t *testing.T) {
+	testVersions(t, db.NewMemory())
+}
+
+func TestPostgresVersions(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	testVersions(t, database)
+}
+
+// testVersions checks that every change to an item bumps its version, and that updates and
+// deletes at a stale version are rejected without changing the item.
+func testVersions(t *testing.T, store db.Storer) {
+	t.Helper()
+
+	ctx := context.Background()
+	scope := db.Scope{OwnerID: "versions-" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+
+	item, err := store.InsertItem(ctx, db.Item{OwnerID: scope.OwnerID, Task: "paint the fence", Status: "TO_BE_STARTED"})
+	if err != nil || item.Version != 1 {
+		t.Fatalf("InsertItem() = %+v, %v, want version 1", item, err)
+	}
+
+	stale := item
+	item.Notes = "white"
+	if item, err = store.UpdateItem(ctx, item); err != nil || item.Version != 2 {
+		t.Fatalf("UpdateItem() = %+v, %v, want version 2", item, err)
+	}
+
+	stale.Notes = "green"
+	if _, err = store.UpdateItem(ctx, stale); !errors.Is(err, apierror.ErrPreconditionFailed) {
+		t.Errorf("UpdateItem() at a stale version error = %v, want %v", err, apierror.ErrPreconditionFailed)
+	}
+
+	// Version zero updates whatever version is stored.
+	item.Version = 0
+	item.Notes = "off-white"
+	if item, err = store.UpdateItem(ctx, item); err != nil || item.Version != 3 {
+		t.Fatalf("UpdateItem() without a version = %+v, %v, want version 3", item, err)
+	}
+
+	if err = store.DeleteItem(ctx, scope, item.ID, 2); !errors.Is(err, apierror.ErrPreconditionFailed) {
+		t.Errorf("DeleteItem() at a stale version error = %v, want %v", err, apierror.ErrPreconditionFailed)
+	}
+
+	if got, getErr := store.GetItem(ctx, scope, item.ID); getErr != nil || got.Notes != "off-white" || got.Version != 3 {
+		t.Errorf("GetItem() = %+v, %v, want it unchanged by the rejected changes", got, getErr)
+	}
+
+	if err = store.DeleteItem(ctx, scope, item.ID, 3); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	restored, err := store.RestoreItem(ctx, scope, item.ID)
+	if err != nil || restored.Version != 5 {
+		t.Errorf("RestoreItem() = %+v, %v, want version 5", restored, err)
+	}
+}
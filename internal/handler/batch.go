@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+// Batch modes.
+const (
+	// BatchAtomic applies all operations of a batch or none of them.
+	BatchAtomic = "atomic"
+
+	// BatchBestEffort applies the operations of a batch that succeed and reports the others.
+	BatchBestEffort = "best_effort"
+)
+
+// TodoBatch is the request body for applying several todo operations at once.
+type TodoBatch struct {
+	// Mode is BatchAtomic or BatchBestEffort. Empty means BatchAtomic.
+	Mode string `json:"mode"`
+
+	Ops []TodoBatchOp `json:"ops"`
+}
+
+// TodoBatchOp is one operation of a batch: a create, update, transition or delete.
+type TodoBatchOp struct {
+	Op string `json:"op"`
+
+	// ID is the todo to update, transition or delete.
+	ID int64 `json:"id"`
+
+	// Version, if set, is the version the todo must be at to be changed, as with If-Match.
+	Version int64 `json:"version"`
+
+	Item     *string      `json:"item"`
+	Status   *string      `json:"status"`
+	DueAt    NullableTime `json:"due_at"`
+	Priority *string      `json:"priority"`
+	Notes    *string      `json:"notes"`
+	Tags     *[]string    `json:"tags"`
+}
+
+// TodoBatchResult is the outcome of one operation of a batch.
+type TodoBatchResult struct {
+	// Index is the position of the operation in the batch.
+	Index int `json:"index"`
+
+	// Status is the HTTP status the operation would have had on its own.
+	Status int `json:"status"`
+
+	Item  *db.Item           `json:"item,omitempty"`
+	Error *apierror.APIError `json:"error,omitempty"`
+}
+
+// TodoBatchResponse is the response body of a batch.
+type TodoBatchResponse struct {
+	Results []TodoBatchResult `json:"results"`
+}
+
+// op returns the service operation for the batch operation.
+func (o TodoBatchOp) op() service.BatchOp {
+	op := service.BatchOp{
+		Op: o.Op,
+
+		ID: o.ID,
+
+		Version: o.Version,
+	}
+
+	switch o.Op {
+	case service.BatchCreate:
+		op.Task = deref(o.Item)
+		op.Details = service.ItemDetails{DueAt: o.DueAt.Value, Priority: deref(o.Priority), Notes: deref(o.Notes)}
+		if o.Tags != nil {
+			op.Details.Tags = *o.Tags
+		}
+	case service.BatchUpdate:
+		op.Patch = service.ItemPatch{
+			Task: o.Item,
+
+			Status: o.Status,
+
+			DueAt: o.DueAt.Value,
+
+			ClearDueAt: o.DueAt.Set && o.DueAt.Value == nil,
+
+			Priority: o.Priority,
+
+			Notes: o.Notes,
+
+			Tags: o.Tags,
+		}
+	case service.BatchTransition:
+		op.Status = deref(o.Status)
+	}
+
+	return op
+}
+
+// Batch applies several todo operations in one transaction. In atomic mode a failing operation
+// fails the whole request; in best-effort mode each operation reports its own outcome.
+// Each operation carries its own version, so If-Match is rejected rather than applied to all
+// of them.
+func (h *Handler) Batch(resp http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("If-Match") != "" {
+		h.handleError(resp, req, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			"If-Match is not supported for batches, set the version of each operation instead",
+		).WithDetails(apierror.FieldError{Field: "If-Match", Message: "must not be set"}))
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var batch TodoBatch
+	if decodeErr := json.NewDecoder(req.Body).Decode(&batch); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	if batch.Mode != "" && batch.Mode != BatchAtomic && batch.Mode != BatchBestEffort {
+		h.handleError(resp, req, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("invalid batch mode %q", batch.Mode),
+		).WithDetails(apierror.FieldError{Field: "mode", Message: "must be atomic or best_effort"}))
+
+		return
+	}
+
+	ops := make([]service.BatchOp, len(batch.Ops))
+	for i, op := range batch.Ops {
+		ops[i] = op.op()
+	}
+
+	results, err := svc.Batch(req.Context(), ops, batch.Mode != BatchBestEffort)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	body := TodoBatchResponse{Results: make([]TodoBatchResult, len(results))}
+	for i, result := range results {
+		body.Results[i] = TodoBatchResult{Index: i, Status: batchStatus(batch.Ops[i].Op), Item: result.Item}
+		if result.Err != nil {
+			body.Results[i].Error = apierror.Response(result.Err, "")
+			body.Results[i].Status = body.Results[i].Error.Code
+			body.Results[i].Item = nil
+		}
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, body)
+}
+
+// batchStatus returns the status of a successful batch operation, the one its own endpoint uses.
+func batchStatus(op string) int {
+	switch op {
+	case service.BatchCreate:
+		return http.StatusCreated
+	case service.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// deref returns the string s points to, or the empty string if s is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
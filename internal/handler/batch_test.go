@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/handler"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		ifMatch      string
+		wantCode     int
+		wantStatuses []int
+		wantErr      apierror.Kind
+		wantDetail   string
+	}{
+		{
+			name: "best effort",
+			body: `{"mode":"best_effort","ops":[
+				{"op":"create","item":"mow the lawn","priority":"high","tags":["garden"]},
+				{"op":"create","item":"paint the fence"},
+				{"op":"update","id":1,"version":1,"notes":"white","due_at":null},
+				{"op":"transition","id":1,"status":"DONE"},
+				{"op":"delete","id":2,"version":3}
+			]}`,
+			wantCode:     http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusConflict, http.StatusOK, http.StatusConflict, http.StatusPreconditionFailed},
+		},
+		{
+			name:         "atomic",
+			body:         `{"ops":[{"op":"create","item":"mow the lawn"},{"op":"delete","id":2}]}`,
+			wantCode:     http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusNoContent},
+		},
+		{
+			name:       "atomic failure",
+			body:       `{"mode":"atomic","ops":[{"op":"create","item":"mow the lawn"},{"op":"transition","id":1,"status":"DONE"}]}`,
+			wantCode:   http.StatusConflict,
+			wantErr:    "invalid_transition",
+			wantDetail: "ops[1]",
+		},
+		{
+			name:       "invalid mode",
+			body:       `{"mode":"eventually","ops":[{"op":"create","item":"mow the lawn"}]}`,
+			wantCode:   http.StatusBadRequest,
+			wantErr:    "invalid_request",
+			wantDetail: "mode",
+		},
+		{
+			name:       "no operations",
+			body:       `{"ops":[]}`,
+			wantCode:   http.StatusBadRequest,
+			wantErr:    "invalid_request",
+			wantDetail: "ops",
+		},
+		{
+			name:       "If-Match",
+			body:       `{"ops":[{"op":"delete","id":1}]}`,
+			ifMatch:    `"1"`,
+			wantCode:   http.StatusBadRequest,
+			wantErr:    "invalid_request",
+			wantDetail: "If-Match",
+		},
+		{
+			name:       "invalid JSON",
+			body:       `{"ops":`,
+			wantCode:   http.StatusBadRequest,
+			wantErr:    "invalid_request",
+			wantDetail: "body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, "paint the fence", "buy paint")
+
+			req := httptest.NewRequest(http.MethodPost, "/todo/batch", strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			h.Batch(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+
+			if tt.wantErr != "" {
+				var apiErr apierror.APIError
+				if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil {
+					t.Fatalf("failed to decode error: %v", err)
+				}
+
+				if apiErr.Kind != tt.wantErr || len(apiErr.Details) == 0 || apiErr.Details[len(apiErr.Details)-1].Field != tt.wantDetail {
+					t.Errorf("expected %s error on %s, got %+v", tt.wantErr, tt.wantDetail, apiErr)
+				}
+
+				return
+			}
+
+			var body handler.TodoBatchResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode results: %v", err)
+			}
+
+			statuses := make([]int, len(body.Results))
+			for i, result := range body.Results {
+				statuses[i] = result.Status
+				if result.Index != i {
+					t.Errorf("expected result %d to have index %d, got %d", i, i, result.Index)
+				}
+
+				if (result.Error != nil) != (result.Status >= http.StatusBadRequest) {
+					t.Errorf("expected result %d to carry an error only if it failed, got %+v", i, result)
+				}
+			}
+
+			if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+				t.Errorf("expected statuses %v, got %v", tt.wantStatuses, statuses)
+			}
+		})
+	}
+}
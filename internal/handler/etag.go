@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+// itemETag returns the entity tag of a todo, which changes with its version.
+func itemETag(item db.Item) string {
+	return `"` + strconv.FormatInt(item.Version, 10) + `"`
+}
+
+// bodyETag returns the entity tag of a response body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeItem writes a todo as a JSON response, tagged with its version.
+func (h *Handler) writeItem(resp http.ResponseWriter, req *http.Request, code int, item db.Item) {
+	resp.Header().Set("ETag", itemETag(item))
+	h.writeJSON(resp, req, code, item)
+}
+
+// ifMatchVersions returns the todo versions listed in the If-Match header of req, and whether
+// the header restricts the versions at all. Weak and malformed tags never match.
+func ifMatchVersions(req *http.Request) ([]int64, bool) {
+	tags := headerTags(req, "If-Match")
+	if len(tags) == 0 || tags[0] == "*" {
+		return nil, false
+	}
+
+	versions := []int64{}
+	for _, tag := range tags {
+		version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err == nil && strings.HasPrefix(tag, `"`) && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, true
+}
+
+// noneMatch reports whether etag is listed in the If-None-Match header of req, comparing weakly.
+func noneMatch(req *http.Request, etag string) bool {
+	for _, tag := range headerTags(req, "If-None-Match") {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// headerTags splits the comma-separated entity tags of a conditional request header.
+func headerTags(req *http.Request, name string) []string {
+	var tags []string
+	for _, value := range req.Header.Values(name) {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETags(t *testing.T) {
+	h := newHandler(t, "paint the fence", "mow the lawn")
+
+	do := func(serve http.HandlerFunc, method, path, id, body string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetPathValue("id", id)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		w := httptest.NewRecorder()
+		serve(w, req)
+
+		return w
+	}
+
+	if got := do(h.Get, http.MethodGet, "/todo/1", "1", "", nil).Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("expected ETag %q, got %q", `"1"`, got)
+	}
+
+	list := do(h.ListTodos, http.MethodGet, "/todo", "", "", nil)
+	listETag := list.Header().Get("ETag")
+	if listETag == "" {
+		t.Fatal("expected an ETag on the collection")
+	}
+
+	tests := []struct {
+		name     string
+		serve    http.HandlerFunc
+		method   string
+		id       string
+		body     string
+		header   http.Header
+		wantCode int
+		wantETag string
+	}{
+		{
+			name:     "item not modified",
+			serve:    h.Get,
+			method:   http.MethodGet,
+			id:       "1",
+			header:   http.Header{"If-None-Match": {`"7", W/"1"`}},
+			wantCode: http.StatusNotModified,
+			wantETag: `"1"`,
+		},
+		{
+			name:     "collection not modified",
+			serve:    h.ListTodos,
+			method:   http.MethodGet,
+			header:   http.Header{"If-None-Match": {listETag}},
+			wantCode: http.StatusNotModified,
+			wantETag: listETag,
+		},
+		{
+			name:     "stale patch",
+			serve:    h.Patch,
+			method:   http.MethodPatch,
+			id:       "1",
+			body:     `{"notes":"white"}`,
+			header:   http.Header{"If-Match": {`"2"`}},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "weak tags never match",
+			serve:    h.Patch,
+			method:   http.MethodPatch,
+			id:       "1",
+			body:     `{"notes":"white"}`,
+			header:   http.Header{"If-Match": {`W/"1"`}},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "matching patch",
+			serve:    h.Patch,
+			method:   http.MethodPatch,
+			id:       "1",
+			body:     `{"notes":"white"}`,
+			header:   http.Header{"If-Match": {`"3", "1"`}},
+			wantCode: http.StatusOK,
+			wantETag: `"2"`,
+		},
+		{
+			name:     "collection modified",
+			serve:    h.ListTodos,
+			method:   http.MethodGet,
+			header:   http.Header{"If-None-Match": {listETag}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "stale update",
+			serve:    h.Update,
+			method:   http.MethodPut,
+			id:       "1",
+			body:     `{"item":"paint the gate","status":"TO_BE_STARTED"}`,
+			header:   http.Header{"If-Match": {`"1"`}},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "stale delete",
+			serve:    h.Delete,
+			method:   http.MethodDelete,
+			id:       "2",
+			header:   http.Header{"If-Match": {`"2"`}},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "delete any version",
+			serve:    h.Delete,
+			method:   http.MethodDelete,
+			id:       "2",
+			header:   http.Header{"If-Match": {"*"}},
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.serve, tt.method, "/todo/"+tt.id, tt.id, tt.body, tt.header)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+
+			if tt.wantETag != "" && w.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("expected ETag %q, got %q", tt.wantETag, w.Header().Get("ETag"))
+			}
+
+			if tt.wantCode == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected no body, got %s", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health statuses reported by the liveness and readiness endpoints.
+const (
+	HealthOK           = "ok"
+	HealthUnavailable  = "unavailable"
+	HealthShuttingDown = "shutting_down"
+)
+
+// defaultCheckTimeout bounds a readiness check when no timeout is configured.
+const defaultCheckTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable, returning an error if it is not.
+type Check func(ctx context.Context) error
+
+// HealthStatus is the response body of the liveness and readiness endpoints.
+type HealthStatus struct {
+	Status string `json:"status"`
+
+	// Checks holds the result of each readiness check by dependency name.
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+
+	// Error is set if the check failed. Details of the failure are only logged.
+	Error string `json:"error,omitempty"`
+}
+
+// namedCheck is a readiness check for a named dependency.
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health serves the liveness and readiness endpoints.
+type Health struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	draining atomic.Bool
+	logger   *slog.Logger
+}
+
+// HealthOption is a function that configures a Health.
+type HealthOption func(*Health)
+
+// WithCheck adds a readiness check for the named dependency.
+func WithCheck(name string, check Check) HealthOption {
+	return func(h *Health) {
+		h.checks = append(h.checks, namedCheck{name: name, check: check})
+	}
+}
+
+// WithCheckTimeout sets how long each readiness check may take.
+// A timeout that is not positive keeps the default.
+func WithCheckTimeout(timeout time.Duration) HealthOption {
+	return func(h *Health) {
+		if timeout > 0 {
+			h.timeout = timeout
+		}
+	}
+}
+
+// WithHealthLogger sets the logger for failed readiness checks.
+func WithHealthLogger(logger *slog.Logger) HealthOption {
+	return func(h *Health) {
+		h.logger = logger
+	}
+}
+
+// NewHealth creates the health endpoints with the given options.
+func NewHealth(opts ...HealthOption) *Health {
+	health := &Health{
+		timeout: defaultCheckTimeout,
+
+		logger: slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(health)
+	}
+
+	return health
+}
+
+// SetDraining marks the server as shutting down, so readiness fails and load balancers
+// stop sending new requests while in-flight ones finish.
+func (h *Health) SetDraining(draining bool) {
+	h.draining.Store(draining)
+}
+
+// Live reports that the process is up. It does not check dependencies, so a broken
+// database does not get the instance restarted.
+func (h *Health) Live(resp http.ResponseWriter, _ *http.Request) {
+	h.write(resp, http.StatusOK, HealthStatus{Status: HealthOK})
+}
+
+// Ready reports whether the server can handle requests, running every readiness check
+// concurrently. It fails with 503 if any check fails or the server is shutting down.
+func (h *Health) Ready(resp http.ResponseWriter, req *http.Request) {
+	if h.draining.Load() {
+		h.write(resp, http.StatusServiceUnavailable, HealthStatus{Status: HealthShuttingDown})
+
+		return
+	}
+
+	status := HealthStatus{
+		Status: HealthOK,
+
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := h.run(req.Context(), c)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			status.Checks[c.name] = result
+			if result.Status != HealthOK {
+				status.Status = HealthUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if status.Status != HealthOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	h.write(resp, code, status)
+}
+
+// run runs a single readiness check with the configured timeout.
+func (h *Health) run(ctx context.Context, c namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+
+	result := CheckResult{
+		Status: HealthOK,
+
+		Latency: time.Since(start).String(),
+	}
+
+	if err != nil {
+		h.logger.WarnContext(ctx, "readiness check failed", "dependency", c.name, "error", err)
+
+		// The error may name hosts and users, so only the log gets it.
+		result.Status = HealthUnavailable
+		result.Error = "unavailable"
+	}
+
+	return result
+}
+
+// write writes a health status as JSON. Health responses are never cached.
+func (h *Health) write(resp http.ResponseWriter, code int, status HealthStatus) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.WriteHeader(code)
+	if err := json.NewEncoder(resp).Encode(status); err != nil {
+		h.logger.Error("failed to encode health response", "error", err)
+	}
+}
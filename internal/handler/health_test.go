@@ -0,0 +1,118 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/handler"
+)
+
+func TestHealth_Live(t *testing.T) {
+	h := handler.NewHealth(handler.WithCheck("database", func(context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	w := httptest.NewRecorder()
+	h.Live(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestHealth_Ready(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name           string
+		checks         map[string]handler.Check
+		draining       bool
+		expectedStatus int
+		expectedHealth string
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "no checks",
+			expectedStatus: http.StatusOK,
+			expectedHealth: handler.HealthOK,
+		},
+		{
+			name:           "all checks pass",
+			checks:         map[string]handler.Check{"database": ok, "cache": ok},
+			expectedStatus: http.StatusOK,
+			expectedHealth: handler.HealthOK,
+			expectedChecks: map[string]string{"database": handler.HealthOK, "cache": handler.HealthOK},
+		},
+		{
+			name:           "one check fails",
+			checks:         map[string]handler.Check{"database": failing, "cache": ok},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedHealth: handler.HealthUnavailable,
+			expectedChecks: map[string]string{"database": handler.HealthUnavailable, "cache": handler.HealthOK},
+		},
+		{
+			name:           "check times out",
+			checks:         map[string]handler.Check{"database": slow},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedHealth: handler.HealthUnavailable,
+			expectedChecks: map[string]string{"database": handler.HealthUnavailable},
+		},
+		{
+			name:           "draining",
+			checks:         map[string]handler.Check{"database": ok},
+			draining:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedHealth: handler.HealthShuttingDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []handler.HealthOption{handler.WithCheckTimeout(10 * time.Millisecond)}
+			for name, check := range tt.checks {
+				opts = append(opts, handler.WithCheck(name, check))
+			}
+
+			h := handler.NewHealth(opts...)
+			h.SetDraining(tt.draining)
+
+			w := httptest.NewRecorder()
+			h.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var response handler.HealthStatus
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if response.Status != tt.expectedHealth {
+				t.Errorf("expected health %q, got %q", tt.expectedHealth, response.Status)
+			}
+
+			for name, expected := range tt.expectedChecks {
+				if got := response.Checks[name].Status; got != expected {
+					t.Errorf("expected check %s to be %q, got %q", name, expected, got)
+				}
+
+				if got := response.Checks[name].Error; strings.Contains(got, "refused") {
+					t.Errorf("expected check %s to hide the error, got %q", name, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// History lists the change history of a todo, oldest first.
+func (h *Handler) History(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	events, err := svc.History(req.Context(), id)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, events)
+}
+
+// AllHistory lists the change history of a todo of any owner or list, oldest first, for
+// administrators. Todos removed for good still have one.
+func (h *Handler) AllHistory(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	events, err := h.todoSvc.AllHistory(req.Context(), id)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, events)
+}
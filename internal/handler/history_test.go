@@ -0,0 +1,94 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+)
+
+func TestHistory(t *testing.T) {
+	h := newHandler(t, "call the bank")
+
+	req := httptest.NewRequest(http.MethodPost, "/todo/1/transitions", strings.NewReader(`{"status":"IN_PROGRESS"}`))
+	req.SetPathValue("id", "1")
+
+	w := httptest.NewRecorder()
+	h.Transition(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	tests := []struct {
+		name        string
+		id          string
+		wantCode    int
+		wantActions []string
+	}{
+		{name: "history", id: "1", wantCode: http.StatusOK, wantActions: []string{db.ActionCreate, db.ActionTransition}},
+		{name: "not found", id: "2", wantCode: http.StatusNotFound},
+		{name: "invalid id", id: "x", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/todo/"+tt.id+"/history", nil)
+			req.SetPathValue("id", tt.id)
+
+			w := httptest.NewRecorder()
+			h.History(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var events []db.Event
+			if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			actions := []string{}
+			for _, event := range events {
+				actions = append(actions, event.Action)
+			}
+			if strings.Join(actions, ",") != strings.Join(tt.wantActions, ",") {
+				t.Errorf("expected actions %v, got %v", tt.wantActions, actions)
+			}
+		})
+	}
+}
+
+func TestAllHistory(t *testing.T) {
+	h := newHandler(t, "call the bank")
+
+	tests := []struct {
+		name     string
+		id       string
+		wantCode int
+	}{
+		{name: "history", id: "1", wantCode: http.StatusOK},
+		{name: "not found", id: "2", wantCode: http.StatusNotFound},
+		{name: "invalid id", id: "x", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/todo/"+tt.id+"/history", nil)
+			req.SetPathValue("id", tt.id)
+
+			w := httptest.NewRecorder()
+			h.AllHistory(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+		})
+	}
+}
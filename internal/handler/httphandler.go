@@ -2,23 +2,96 @@ package handler
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+	"github.com/brkcnr/golandworks-api/internal/requestid"
 	"github.com/brkcnr/golandworks-api/internal/service"
 )
 
 // TodoItem is a todo item.
 type TodoItem struct {
-	Item string `json:"item"`
+	Item     string     `json:"item"`
+	DueAt    *time.Time `json:"due_at"`
+	Priority string     `json:"priority"`
+	Notes    string     `json:"notes"`
+	Tags     []string   `json:"tags"`
+}
+
+// TodoUpdate is the request body for replacing a todo item.
+type TodoUpdate struct {
+	Item     string     `json:"item"`
+	Status   string     `json:"status"`
+	DueAt    *time.Time `json:"due_at"`
+	Priority string     `json:"priority"`
+	Notes    string     `json:"notes"`
+	Tags     []string   `json:"tags"`
+}
+
+// TodoTransition is the request body for changing the status of a todo item.
+type TodoTransition struct {
+	Status string `json:"status"`
+}
+
+// TodoPatch is the request body for partially updating a todo item.
+type TodoPatch struct {
+	Item     *string      `json:"item"`
+	Status   *string      `json:"status"`
+	DueAt    NullableTime `json:"due_at"`
+	Priority *string      `json:"priority"`
+	Notes    *string      `json:"notes"`
+	Tags     *[]string    `json:"tags"`
+}
+
+// NullableTime is a JSON time that records whether it was present, so that null can
+// be told apart from a missing field.
+type NullableTime struct {
+	// Set reports whether the field was present.
+	Set bool
+
+	// Value is the time, or nil if the field was null.
+	Value *time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *NullableTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	if string(data) == "null" {
+		t.Value = nil
+
+		return nil
+	}
+
+	var value time.Time
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	t.Value = &value
+
+	return nil
+}
+
+// details returns the optional fields of the todo item.
+func (t TodoItem) details() service.ItemDetails {
+	return service.ItemDetails{
+		DueAt: t.DueAt,
+
+		Priority: t.Priority,
+
+		Notes: t.Notes,
+
+		Tags: t.Tags,
+	}
 }
 
 // Handler is a HTTP handler.
 type Handler struct {
 	todoSvc *service.TodoService
-	logger  *log.Logger
+	logger  *slog.Logger
 }
 
 // Option is a function that configures a Handler.
@@ -31,17 +104,24 @@ func WithTodoService(svc *service.TodoService) Option {
 	}
 }
 
-// WithLogger sets the logger.
-func WithLogger(logger *log.Logger) Option {
+// WithLogger sets the logger used for requests that do not carry their own.
+func WithLogger(logger *slog.Logger) Option {
 	return func(h *Handler) {
 		h.logger = logger
 	}
 }
 
+// WithLogHandler sets the logger to one that sends records to handler.
+func WithLogHandler(handler slog.Handler) Option {
+	return func(h *Handler) {
+		h.logger = slog.New(handler)
+	}
+}
+
 // New creates a new HTTP handler with the given options.
 func New(opts ...Option) *Handler {
 	handler := &Handler{
-		logger: log.Default(), // Set default logger
+		logger: slog.Default(), // Set default logger
 	}
 
 	// Apply all options
@@ -52,88 +132,523 @@ func New(opts ...Option) *Handler {
 	return handler
 }
 
-// ListTodos lists all todos.
+// ListTodos lists a page of the caller's todos, or of the todos of the list in the path.
+// It accepts the status, priority and tag (all repeatable), tag_match, overdue, due_before,
+// due_after, sort, limit, cursor and offset query parameters.
 func (h *Handler) ListTodos(resp http.ResponseWriter, req *http.Request) {
-	todoItems, err := h.todoSvc.ListTodos(req.Context())
+	opts, err := listOptions(req)
 	if err != nil {
-		h.logger.Println(err)
-		resp.WriteHeader(http.StatusInternalServerError)
+		h.handleError(resp, req, err)
 
 		return
 	}
-	jsonBytes, err := json.Marshal(todoItems)
+
+	svc, err := h.service(req)
 	if err != nil {
-		h.logger.Println(err)
-		resp.WriteHeader(http.StatusInternalServerError)
+		h.handleError(resp, req, err)
 
 		return
 	}
-	if _, err = resp.Write(jsonBytes); err != nil {
-		h.logger.Println(err)
+
+	page, err := svc.ListTodos(req.Context(), opts)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, page)
+}
+
+// ListAllTodos lists a page of the todos of every owner, for administrators.
+// It accepts the parameters of ListTodos, and owner to keep only the todos of one owner.
+func (h *Handler) ListAllTodos(resp http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	page, err := h.todoSvc.ListAllTodos(req.Context(), req.URL.Query().Get("owner"), opts)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, page)
+}
+
+// listOptions reads the list query parameters of req.
+func listOptions(req *http.Request) (service.ListOptions, error) {
+	params := req.URL.Query()
+
+	limit, err := intParam(params.Get("limit"), "limit")
+	if err != nil {
+		return service.ListOptions{}, err
 	}
+
+	offset, err := intParam(params.Get("offset"), "offset")
+	if err != nil {
+		return service.ListOptions{}, err
+	}
+
+	overdue, err := boolParam(params.Get("overdue"), "overdue")
+	if err != nil {
+		return service.ListOptions{}, err
+	}
+
+	dueBefore, err := timeParam(params.Get("due_before"), "due_before")
+	if err != nil {
+		return service.ListOptions{}, err
+	}
+
+	dueAfter, err := timeParam(params.Get("due_after"), "due_after")
+	if err != nil {
+		return service.ListOptions{}, err
+	}
+
+	allTags, err := tagMatchParam(params.Get("tag_match"))
+	if err != nil {
+		return service.ListOptions{}, err
+	}
+
+	return service.ListOptions{
+		Statuses: params["status"],
+
+		Priorities: params["priority"],
+
+		Overdue: overdue,
+
+		DueBefore: dueBefore,
+
+		DueAfter: dueAfter,
+
+		Tags: params["tag"],
+
+		AllTags: allTags,
+
+		Sort: params.Get("sort"),
+
+		Limit: limit,
+
+		Cursor: params.Get("cursor"),
+
+		Offset: offset,
+	}, nil
 }
 
 // Add adds a todo.
 func (h *Handler) Add(resp http.ResponseWriter, req *http.Request) {
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
 	var todoItem TodoItem
 	if err := json.NewDecoder(req.Body).Decode(&todoItem); err != nil {
-		h.handleError(resp, apierror.Wrap(err, http.StatusBadRequest, "invalid JSON request"))
+		h.handleError(resp, req, invalidJSON(err))
+
+		return
+	}
+
+	item, err := svc.Add(req.Context(), todoItem.Item, todoItem.details())
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusCreated, item)
+}
+
+// Get returns a single todo.
+func (h *Handler) Get(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	item, err := svc.Get(req.Context(), id)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusOK, item)
+}
+
+// Update replaces a todo.
+func (h *Handler) Update(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var update TodoUpdate
+	if decodeErr := json.NewDecoder(req.Body).Decode(&update); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	item, err := svc.Update(req.Context(), id, update.Item, update.Status, service.ItemDetails{
+		DueAt: update.DueAt,
+
+		Priority: update.Priority,
+
+		Notes: update.Notes,
+
+		Tags: update.Tags,
+	})
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusOK, item)
+}
+
+// Patch partially updates a todo.
+func (h *Handler) Patch(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var patch TodoPatch
+	if decodeErr := json.NewDecoder(req.Body).Decode(&patch); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	item, err := svc.Patch(req.Context(), id, service.ItemPatch{
+		Task: patch.Item,
+
+		Status: patch.Status,
+
+		DueAt: patch.DueAt.Value,
+
+		ClearDueAt: patch.DueAt.Set && patch.DueAt.Value == nil,
+
+		Priority: patch.Priority,
+
+		Notes: patch.Notes,
+
+		Tags: patch.Tags,
+	})
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusOK, item)
+}
+
+// Transition moves a todo to a new status.
+func (h *Handler) Transition(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var transition TodoTransition
+	if decodeErr := json.NewDecoder(req.Body).Decode(&transition); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	item, err := svc.Transition(req.Context(), id, transition.Status)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusOK, item)
+}
+
+// Delete deletes a todo.
+func (h *Handler) Delete(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	if err = svc.Delete(req.Context(), id); err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Tags lists the tags of the caller's todos, or of the list in the path, with usage counts.
+func (h *Handler) Tags(resp http.ResponseWriter, req *http.Request) {
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
 
 		return
 	}
 
-	if err := h.todoSvc.Add(req.Context(), todoItem.Item); err != nil {
-		h.handleError(resp, err)
+	tags, err := svc.Tags(req.Context())
+	if err != nil {
+		h.handleError(resp, req, err)
 
 		return
 	}
 
-	resp.WriteHeader(http.StatusCreated)
+	h.writeJSON(resp, req, http.StatusOK, tags)
 }
 
-// Search searches for todos that contain the query.
+// Search searches for todos that match the query.
 func (h *Handler) Search(resp http.ResponseWriter, req *http.Request) {
-	query := req.URL.Query().Get("q")
-	if query == "" {
-		resp.WriteHeader(http.StatusBadRequest)
+	params := req.URL.Query()
+
+	limit, err := intParam(params.Get("limit"), "limit")
+	if err != nil {
+		h.handleError(resp, req, err)
 
 		return
 	}
-	results, err := h.todoSvc.Search(req.Context(), query)
+
+	offset, err := intParam(params.Get("offset"), "offset")
 	if err != nil {
-		h.logger.Print(err.Error())
-		resp.WriteHeader(http.StatusInternalServerError)
+		h.handleError(resp, req, err)
 
 		return
 	}
-	jsonBytes, err := json.Marshal(results)
+
+	page, err := h.todoSvc.Search(req.Context(), params.Get("q"), limit, offset)
 	if err != nil {
-		resp.WriteHeader(http.StatusInternalServerError)
+		h.handleError(resp, req, err)
 
 		return
 	}
-	if _, err = resp.Write(jsonBytes); err != nil {
-		h.logger.Println(err)
+
+	h.writeJSON(resp, req, http.StatusOK, page)
+}
+
+// service returns the todo service for req: scoped to the list in the path if there is one,
+// otherwise acting on the caller's personal todos. Its changes are conditional on the versions
+// in the If-Match header.
+func (h *Handler) service(req *http.Request) (*service.TodoService, error) {
+	svc := h.todoSvc
+	if versions, ok := ifMatchVersions(req); ok {
+		svc = svc.IfMatch(versions...)
 	}
+
+	if req.PathValue("list") == "" {
+		return svc, nil
+	}
+
+	listID, err := parseListID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.ForList(listID), nil
 }
 
-// handleError handles an error.
-func (h *Handler) handleError(resp http.ResponseWriter, err error) {
-	h.logger.Printf("Error: %v", err)
+// parseID parses the todo ID from the request path.
+func parseID(req *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid todo id").
+			WithDetails(apierror.FieldError{Field: "id", Message: "must be a positive integer"})
+	}
 
-	var apiErr *apierror.APIError
-	if errors.As(err, &apiErr) {
-		resp.WriteHeader(apiErr.Code)
-		if encodeErr := json.NewEncoder(resp).Encode(apiErr); encodeErr != nil {
-			h.logger.Printf("Failed to encode error response: %v", encodeErr)
-		}
+	return id, nil
+}
+
+// intParam parses an optional integer query parameter, returning zero if it is empty.
+func intParam(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid "+name+" parameter").
+			WithDetails(apierror.FieldError{Field: name, Message: "must be an integer"})
+	}
+
+	return n, nil
+}
+
+// boolParam parses an optional boolean query parameter, returning false if it is empty.
+func boolParam(value, name string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid "+name+" parameter").
+			WithDetails(apierror.FieldError{Field: name, Message: "must be true or false"})
+	}
+
+	return b, nil
+}
+
+// timeParam parses an optional RFC 3339 time query parameter, returning the zero time if it is empty.
+func timeParam(value, name string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid "+name+" parameter").
+			WithDetails(apierror.FieldError{Field: name, Message: "must be an RFC 3339 time"})
+	}
+
+	return t, nil
+}
+
+// tagMatchParam parses the tag_match query parameter, reporting whether todos must have all
+// the tags rather than any of them. It defaults to any.
+func tagMatchParam(value string) (bool, error) {
+	switch value {
+	case "", "any":
+		return false, nil
+	case "all":
+		return true, nil
+	default:
+		return false, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid tag_match parameter").
+			WithDetails(apierror.FieldError{Field: "tag_match", Message: "must be any or all"})
+	}
+}
+
+// invalidJSON returns the error for a request body that could not be decoded.
+func invalidJSON(err error) error {
+	return apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid JSON request").
+		WithDetails(apierror.FieldError{Field: "body", Message: err.Error()})
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+// Successful GET responses carry an ETag, the one already set or one derived from the body, and
+// are answered with 304 Not Modified if the request's If-None-Match lists it.
+func (h *Handler) writeJSON(resp http.ResponseWriter, req *http.Request, code int, v any) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		h.handleError(resp, req, err)
 
 		return
 	}
 
-	// Default to internal server error
-	resp.WriteHeader(http.StatusInternalServerError)
-	if encodeErr := json.NewEncoder(resp).Encode(apierror.ErrInternalServer); encodeErr != nil {
-		h.logger.Printf("Failed to encode default error response: %v", encodeErr)
+	if req.Method == http.MethodGet && code == http.StatusOK {
+		etag := resp.Header().Get("ETag")
+		if etag == "" {
+			etag = bodyETag(jsonBytes)
+			resp.Header().Set("ETag", etag)
+		}
+
+		if noneMatch(req, etag) {
+			resp.WriteHeader(http.StatusNotModified)
+
+			return
+		}
 	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	if _, err = resp.Write(jsonBytes); err != nil {
+		h.log(req).ErrorContext(req.Context(), "failed to write response", "error", err)
+	}
+}
+
+// handleError writes err as a JSON error response tagged with the request ID.
+// Errors that are not APIErrors are reported as internal server errors.
+func (h *Handler) handleError(resp http.ResponseWriter, req *http.Request, err error) {
+	id := requestID(req)
+	apiErr := apierror.Response(err, id)
+
+	level := slog.LevelInfo
+	if apiErr.Code >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger := h.log(req)
+	if requestid.FromContext(req.Context()) == "" {
+		// Requests from the server already log their ID through the request logger.
+		logger = logger.With("request_id", id)
+	}
+	logger.Log(req.Context(), level, "request failed", "status", apiErr.Code, "kind", apiErr.Kind, "error", err)
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set(requestid.Header, id)
+	resp.WriteHeader(apiErr.Code)
+	if encodeErr := json.NewEncoder(resp).Encode(apiErr); encodeErr != nil {
+		h.log(req).ErrorContext(req.Context(), "failed to encode error response", "error", encodeErr)
+	}
+}
+
+// log returns the logger for req, which carries the request attributes when the server set them.
+func (h *Handler) log(req *http.Request) *slog.Logger {
+	return logging.FromContext(req.Context(), h.logger)
+}
+
+// requestID returns the ID of req from its context, or a new one. The header is not read here:
+// only the server's RequestID middleware decides whether a client's ID is trusted.
+func requestID(req *http.Request) string {
+	if id := requestid.FromContext(req.Context()); id != "" {
+		return id
+	}
+
+	return requestid.New()
 }
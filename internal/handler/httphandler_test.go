@@ -4,45 +4,42 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
+	"github.com/brkcnr/golandworks-api/internal/apierror"
 	"github.com/brkcnr/golandworks-api/internal/db"
 	"github.com/brkcnr/golandworks-api/internal/handler"
+	"github.com/brkcnr/golandworks-api/internal/requestid"
 	"github.com/brkcnr/golandworks-api/internal/service"
 )
 
-// MockDB implements db.Storer interface for testing
-type MockDB struct {
-	insertItemFunc  func(ctx context.Context, item db.Item) error
-	getAllItemsFunc func(ctx context.Context) ([]db.Item, error)
-}
-
-func (m *MockDB) InsertItem(ctx context.Context, item db.Item) error {
-	return m.insertItemFunc(ctx, item)
-}
+// newHandler returns a handler backed by an in-memory store seeded with tasks.
+// Seeded items are assigned IDs starting at 1 and have status TO_BE_STARTED.
+func newHandler(t *testing.T, tasks ...string) *handler.Handler {
+	t.Helper()
 
-func (m *MockDB) GetAllItems(ctx context.Context) ([]db.Item, error) {
-	return m.getAllItemsFunc(ctx)
-}
-
-func TestListTodos(t *testing.T) {
-	mockDB := &MockDB{
-		getAllItemsFunc: func(ctx context.Context) ([]db.Item, error) {
-			return []db.Item{
-				{Task: "todo1", Status: "TO_BE_STARTED"},
-				{Task: "todo2", Status: "TO_BE_STARTED"},
-			}, nil
-		},
+	store := db.NewMemory()
+	for _, task := range tasks {
+		if _, err := store.InsertItem(context.Background(), db.Item{Task: task, Status: "TO_BE_STARTED"}); err != nil {
+			t.Fatalf("failed to seed store: %v", err)
+		}
 	}
 
-	todoService := service.New(service.WithDB(mockDB))
-	h := handler.New(
+	todoService := service.New(service.WithDB(store))
+
+	return handler.New(
 		handler.WithTodoService(todoService),
-		handler.WithLogger(log.Default()),
+		handler.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
 	)
+}
+
+func TestListTodos(t *testing.T) {
+	h := newHandler(t, "todo1", "todo2")
 
 	req := httptest.NewRequest(http.MethodGet, "/todos", nil)
 	w := httptest.NewRecorder()
@@ -53,32 +50,179 @@ func TestListTodos(t *testing.T) {
 		t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var response []db.Item
+	var response service.ListPage
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if len(response) != 2 {
-		t.Errorf("expected 2 items, got %d", len(response))
+	if len(response.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(response.Items))
 	}
 }
 
-func TestAdd(t *testing.T) {
-	mockDB := &MockDB{
-		insertItemFunc: func(ctx context.Context, item db.Item) error {
-			return nil
+func TestListTodos_Query(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTasks  []string
+		expectedCursor bool
+	}{
+		{
+			name:           "sorted by task descending",
+			query:          "?sort=-task",
+			expectedStatus: http.StatusOK,
+			expectedTasks:  []string{"todo3", "todo2", "todo1"},
+		},
+		{
+			name:           "first page",
+			query:          "?limit=2",
+			expectedStatus: http.StatusOK,
+			expectedTasks:  []string{"todo1", "todo2"},
+			expectedCursor: true,
+		},
+		{
+			name:           "filtered by status",
+			query:          "?status=IN_PROGRESS&status=DONE",
+			expectedStatus: http.StatusOK,
+			expectedTasks:  []string{},
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=owner",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "filtered by priority",
+			query:          "?priority=normal&sort=-priority",
+			expectedStatus: http.StatusOK,
+			expectedTasks:  []string{"todo3", "todo2", "todo1"},
 		},
-		getAllItemsFunc: func(ctx context.Context) ([]db.Item, error) {
-			return []db.Item{}, nil
+		{
+			name:           "overdue",
+			query:          "?overdue=true",
+			expectedStatus: http.StatusOK,
+			expectedTasks:  []string{},
+		},
+		{
+			name:           "invalid priority",
+			query:          "?priority=extreme",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid overdue",
+			query:          "?overdue=yes",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid due date",
+			query:          "?due_before=tomorrow",
+			expectedStatus: http.StatusBadRequest,
 		},
 	}
 
-	todoService := service.New(service.WithDB(mockDB))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, "todo1", "todo2", "todo3")
+
+			req := httptest.NewRequest(http.MethodGet, "/todo"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.ListTodos(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response service.ListPage
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			tasks := []string{}
+			for _, item := range response.Items {
+				tasks = append(tasks, item.Task)
+			}
+
+			if !slices.Equal(tasks, tt.expectedTasks) {
+				t.Errorf("expected tasks %v, got %v", tt.expectedTasks, tasks)
+			}
+
+			if (response.NextCursor != "") != tt.expectedCursor {
+				t.Errorf("expected cursor %v, got %q", tt.expectedCursor, response.NextCursor)
+			}
+		})
+	}
+}
+
+func TestListAllTodos(t *testing.T) {
+	store := db.NewMemory()
+	for _, item := range []db.Item{
+		{OwnerID: "alice", Task: "todo1", Status: "TO_BE_STARTED"},
+		{OwnerID: "bob", Task: "todo1", Status: "TO_BE_STARTED"},
+		{OwnerID: "bob", Task: "todo2", Status: "DONE"},
+	} {
+		if _, err := store.InsertItem(context.Background(), item); err != nil {
+			t.Fatalf("failed to seed store: %v", err)
+		}
+	}
+
 	h := handler.New(
-		handler.WithTodoService(todoService),
-		handler.WithLogger(log.Default()),
+		handler.WithTodoService(service.New(service.WithDB(store))),
+		handler.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
 	)
 
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantItems int
+	}{
+		{name: "every owner", query: "", wantCode: http.StatusOK, wantItems: 3},
+		{name: "one owner", query: "?owner=bob", wantCode: http.StatusOK, wantItems: 2},
+		{name: "owner and status", query: "?owner=bob&status=DONE", wantCode: http.StatusOK, wantItems: 1},
+		{name: "invalid limit", query: "?limit=x", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/todo"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.ListAllTodos(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d", tt.wantCode, w.Code)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response service.ListPage
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(response.Items) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(response.Items))
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	h := newHandler(t)
+
 	body := bytes.NewBufferString(`{"item": "test todo"}`)
 	req := httptest.NewRequest(http.MethodPost, "/todos", body)
 	w := httptest.NewRecorder()
@@ -88,23 +232,325 @@ func TestAdd(t *testing.T) {
 	if w.Code != http.StatusCreated {
 		t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
 	}
+
+	var response db.Item
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.ID != 1 || response.Task != "test todo" {
+		t.Errorf("unexpected response: %v", response)
+	}
 }
 
-func TestSearch(t *testing.T) {
-	mockDB := &MockDB{
-		getAllItemsFunc: func(ctx context.Context) ([]db.Item, error) {
-			return []db.Item{
-				{Task: "test todo", Status: "TO_BE_STARTED"},
-				{Task: "another task", Status: "TO_BE_STARTED"},
-			}, nil
+func TestAdd_Duplicate(t *testing.T) {
+	h := newHandler(t, "test todo")
+
+	body := bytes.NewBufferString(`{"item": "test todo"}`)
+	req := httptest.NewRequest(http.MethodPost, "/todos", body)
+	w := httptest.NewRecorder()
+
+	h.Add(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	var response apierror.APIError
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Kind != apierror.ErrDuplicateTodo.Kind || response.Code != http.StatusConflict {
+		t.Errorf("unexpected error response: %+v", response)
+	}
+}
+
+func TestGet(t *testing.T) {
+	h := newHandler(t, "todo1")
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{name: "existing todo", id: "1", expectedStatus: http.StatusOK},
+		{name: "missing todo", id: "2", expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/todo/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.Get(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedItem   db.Item
+	}{
+		{
+			name:           "replace todo",
+			method:         http.MethodPut,
+			body:           `{"item": "todo1 renamed", "status": "IN_PROGRESS"}`,
+			expectedStatus: http.StatusOK,
+			expectedItem:   db.Item{ID: 1, Task: "todo1 renamed", Status: "IN_PROGRESS"},
+		},
+		{
+			name:           "replace without status",
+			method:         http.MethodPut,
+			body:           `{"item": "todo1 renamed"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "patch status only",
+			method:         http.MethodPatch,
+			body:           `{"status": "IN_PROGRESS"}`,
+			expectedStatus: http.StatusOK,
+			expectedItem:   db.Item{ID: 1, Task: "todo1", Status: "IN_PROGRESS"},
+		},
+		{
+			name:           "patch illegal transition",
+			method:         http.MethodPatch,
+			body:           `{"status": "DONE"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid JSON",
+			method:         http.MethodPatch,
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
 		},
 	}
 
-	todoService := service.New(service.WithDB(mockDB))
-	h := handler.New(
-		handler.WithTodoService(todoService),
-		handler.WithLogger(log.Default()),
-	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, "todo1")
+
+			req := httptest.NewRequest(tt.method, "/todo/1", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", "1")
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodPut {
+				h.Update(w, req)
+			} else {
+				h.Patch(w, req)
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response db.Item
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if response.ID != tt.expectedItem.ID || response.Task != tt.expectedItem.Task ||
+				response.Status != tt.expectedItem.Status {
+				t.Errorf("expected %v, got %v", tt.expectedItem, response)
+			}
+		})
+	}
+}
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "allowed transition", body: `{"status": "IN_PROGRESS"}`, expectedStatus: http.StatusOK},
+		{name: "illegal transition", body: `{"status": "DONE"}`, expectedStatus: http.StatusConflict},
+		{name: "unknown status", body: `{"status": "SOMEDAY"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, "todo1")
+
+			req := httptest.NewRequest(http.MethodPost, "/todo/1/transitions", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", "1")
+			w := httptest.NewRecorder()
+
+			h.Transition(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestDetails(t *testing.T) {
+	h := newHandler(t)
+
+	do := func(serve http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/todo/1", bytes.NewBufferString(body))
+		req.SetPathValue("id", "1")
+		w := httptest.NewRecorder()
+		serve(w, req)
+
+		return w
+	}
+
+	w := do(h.Add, `{"item": "File taxes", "due_at": "2030-01-10T12:00:00Z", "priority": "high", "notes": "ask the accountant"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	tests := []struct {
+		name         string
+		serve        http.HandlerFunc
+		body         string
+		wantCode     int
+		wantDue      bool
+		wantPriority string
+	}{
+		{name: "patch priority keeps due date", serve: h.Patch, body: `{"priority": "urgent"}`, wantCode: http.StatusOK, wantDue: true, wantPriority: "urgent"},
+		{name: "patch null clears due date", serve: h.Patch, body: `{"due_at": null}`, wantCode: http.StatusOK, wantPriority: "urgent"},
+		{name: "patch sets due date", serve: h.Patch, body: `{"due_at": "2030-02-01T09:00:00+01:00"}`, wantCode: http.StatusOK, wantDue: true, wantPriority: "urgent"},
+		{name: "put resets omitted details", serve: h.Update, body: `{"item": "File taxes", "status": "TO_BE_STARTED"}`, wantCode: http.StatusOK, wantPriority: "normal"},
+		{name: "invalid due date", serve: h.Patch, body: `{"due_at": "tomorrow"}`, wantCode: http.StatusBadRequest},
+		{name: "invalid priority", serve: h.Patch, body: `{"priority": "asap"}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.serve, tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var item db.Item
+			if err := json.NewDecoder(w.Body).Decode(&item); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if (item.DueAt != nil) != tt.wantDue || item.Priority != tt.wantPriority {
+				t.Errorf("unexpected response: %+v", item)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	h := newHandler(t)
+
+	for _, body := range []string{
+		`{"item": "groceries", "tags": ["shopping", "home"]}`,
+		`{"item": "hardware", "tags": ["Shopping"]}`,
+	} {
+		w := httptest.NewRecorder()
+		h.Add(w, httptest.NewRequest(http.MethodPost, "/todo", bytes.NewBufferString(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantTasks []string
+	}{
+		{name: "any tag", query: "?tag=home&tag=shopping", wantCode: http.StatusOK, wantTasks: []string{"groceries", "hardware"}},
+		{name: "all tags", query: "?tag=home&tag=shopping&tag_match=all", wantCode: http.StatusOK, wantTasks: []string{"groceries"}},
+		{name: "invalid match", query: "?tag=home&tag_match=some", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ListTodos(w, httptest.NewRequest(http.MethodGet, "/todo"+tt.query, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d", tt.wantCode, w.Code)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response service.ListPage
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			tasks := []string{}
+			for _, item := range response.Items {
+				tasks = append(tasks, item.Task)
+			}
+			if !slices.Equal(tasks, tt.wantTasks) {
+				t.Errorf("expected tasks %v, got %v", tt.wantTasks, tasks)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.Tags(w, httptest.NewRequest(http.MethodGet, "/tags", nil))
+
+	var counts []db.TagCount
+	if err := json.NewDecoder(w.Body).Decode(&counts); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := []db.TagCount{{Tag: "shopping", Count: 2}, {Tag: "home", Count: 1}}
+	if !slices.Equal(counts, want) {
+		t.Errorf("expected tags %v, got %v", want, counts)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	h := newHandler(t, "todo1")
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{name: "existing todo", id: "1", expectedStatus: http.StatusNoContent},
+		{name: "already deleted todo", id: "1", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/todo/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.Delete(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h := newHandler(t, "test todo", "another task")
 
 	req := httptest.NewRequest(http.MethodGet, "/todos/search?q=test", nil)
 	w := httptest.NewRecorder()
@@ -115,28 +561,31 @@ func TestSearch(t *testing.T) {
 		t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var response []string
+	var response service.SearchPage
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if len(response) != 1 || response[0] != "test todo" {
+	if len(response.Results) != 1 || response.Results[0].Task != "test todo" || response.Results[0].ID != 1 {
 		t.Errorf("unexpected response: %v", response)
 	}
 }
 
-func TestSearch_EmptyQuery(t *testing.T) {
-	mockDB := &MockDB{
-		getAllItemsFunc: func(ctx context.Context) ([]db.Item, error) {
-			return []db.Item{}, nil
-		},
+func TestSearch_InvalidLimit(t *testing.T) {
+	h := newHandler(t, "test todo")
+
+	req := httptest.NewRequest(http.MethodGet, "/todos/search?q=test&limit=abc", nil)
+	w := httptest.NewRecorder()
+
+	h.Search(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
 	}
+}
 
-	todoService := service.New(service.WithDB(mockDB))
-	h := handler.New(
-		handler.WithTodoService(todoService),
-		handler.WithLogger(log.Default()),
-	)
+func TestSearch_EmptyQuery(t *testing.T) {
+	h := newHandler(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/todos/search", nil)
 	w := httptest.NewRecorder()
@@ -147,3 +596,81 @@ func TestSearch_EmptyQuery(t *testing.T) {
 		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		requestID     string
+		header        string
+		expectedError string
+		expectedField string
+	}{
+		{
+			name:          "invalid limit",
+			query:         "?limit=abc",
+			requestID:     "req-1",
+			expectedError: "invalid_request",
+			expectedField: "limit",
+		},
+		{
+			name:          "untrusted request ID header",
+			query:         "?limit=abc",
+			header:        "req-2",
+			expectedError: "invalid_request",
+			expectedField: "limit",
+		},
+		{
+			name:          "invalid status",
+			query:         "?status=SOMEDAY",
+			expectedError: "invalid_status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/todo"+tt.query, nil)
+			if tt.requestID != "" {
+				req = req.WithContext(requestid.NewContext(req.Context(), tt.requestID))
+			}
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			h.ListTodos(w, req)
+
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("expected content type application/json, got %q", got)
+			}
+
+			var response apierror.APIError
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if response.Code != w.Code || string(response.Kind) != tt.expectedError {
+				t.Errorf("unexpected error response: %+v", response)
+			}
+
+			if response.RequestID == "" || response.RequestID != w.Header().Get(requestid.Header) {
+				t.Errorf("expected request ID in body and header, got %q and %q",
+					response.RequestID, w.Header().Get(requestid.Header))
+			}
+
+			if tt.requestID != "" && response.RequestID != tt.requestID {
+				t.Errorf("expected request ID %q, got %q", tt.requestID, response.RequestID)
+			}
+
+			if tt.header != "" && response.RequestID == tt.header {
+				t.Errorf("expected request ID header %q to be ignored", tt.header)
+			}
+
+			if tt.expectedField != "" && (len(response.Details) != 1 || response.Details[0].Field != tt.expectedField) {
+				t.Errorf("expected details for field %q, got %v", tt.expectedField, response.Details)
+			}
+		})
+	}
+}
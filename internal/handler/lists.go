@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+)
+
+// ListCreate is the request body for creating a list.
+type ListCreate struct {
+	Name string `json:"name"`
+}
+
+// MemberRole is the request body for adding a list member or changing their role.
+type MemberRole struct {
+	Role string `json:"role"`
+}
+
+// CreateList creates a list owned by the caller.
+func (h *Handler) CreateList(resp http.ResponseWriter, req *http.Request) {
+	var create ListCreate
+	if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+		h.handleError(resp, req, invalidJSON(err))
+
+		return
+	}
+
+	list, err := h.todoSvc.CreateList(req.Context(), create.Name)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusCreated, list)
+}
+
+// Lists lists the lists the caller is a member of.
+func (h *Handler) Lists(resp http.ResponseWriter, req *http.Request) {
+	lists, err := h.todoSvc.Lists(req.Context())
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, lists)
+}
+
+// GetList returns a single list.
+func (h *Handler) GetList(resp http.ResponseWriter, req *http.Request) {
+	listID, err := parseListID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	list, err := h.todoSvc.GetList(req.Context(), listID)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, list)
+}
+
+// DeleteList deletes a list and its todos.
+func (h *Handler) DeleteList(resp http.ResponseWriter, req *http.Request) {
+	listID, err := parseListID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	if err = h.todoSvc.DeleteList(req.Context(), listID); err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Members lists the members of a list.
+func (h *Handler) Members(resp http.ResponseWriter, req *http.Request) {
+	listID, err := parseListID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	members, err := h.todoSvc.Members(req.Context(), listID)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, members)
+}
+
+// SetMember adds a member to a list or changes their role.
+func (h *Handler) SetMember(resp http.ResponseWriter, req *http.Request) {
+	listID, err := parseListID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var role MemberRole
+	if decodeErr := json.NewDecoder(req.Body).Decode(&role); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	member, err := h.todoSvc.SetMember(req.Context(), listID, req.PathValue("member"), role.Role)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, member)
+}
+
+// RemoveMember removes a member from a list.
+func (h *Handler) RemoveMember(resp http.ResponseWriter, req *http.Request) {
+	listID, err := parseListID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	if err = h.todoSvc.RemoveMember(req.Context(), listID, req.PathValue("member")); err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// parseListID parses the list ID from the request path.
+func parseListID(req *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(req.PathValue("list"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid list id").
+			WithDetails(apierror.FieldError{Field: "list", Message: "must be a positive integer"})
+	}
+
+	return id, nil
+}
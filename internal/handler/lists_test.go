@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/handler"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestLists(t *testing.T) {
+	h := handler.New(
+		handler.WithTodoService(service.New(service.WithDB(db.NewMemory()))),
+		handler.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	// do calls serve as user with the path values, which alternate between name and value.
+	do := func(serve http.HandlerFunc, user, body string, pathValues ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/lists", strings.NewReader(body))
+		req = req.WithContext(auth.NewContext(context.Background(), auth.Principal{Subject: user}))
+		for i := 0; i+1 < len(pathValues); i += 2 {
+			req.SetPathValue(pathValues[i], pathValues[i+1])
+		}
+
+		w := httptest.NewRecorder()
+		serve(w, req)
+
+		return w
+	}
+
+	w := do(h.CreateList, "alice", `{"name":"Groceries"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateList returned %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	var list db.List
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		serve      http.HandlerFunc
+		user       string
+		body       string
+		pathValues []string
+		wantCode   int
+	}{
+		{name: "create without name", serve: h.CreateList, user: "alice", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "create with invalid JSON", serve: h.CreateList, user: "alice", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "get", serve: h.GetList, user: "alice", pathValues: []string{"list", "1"}, wantCode: http.StatusOK},
+		{name: "get as non-member", serve: h.GetList, user: "bob", pathValues: []string{"list", "1"}, wantCode: http.StatusNotFound},
+		{name: "get with invalid id", serve: h.GetList, user: "alice", pathValues: []string{"list", "x"}, wantCode: http.StatusBadRequest},
+		{
+			name:       "add viewer",
+			serve:      h.SetMember,
+			user:       "alice",
+			body:       `{"role":"viewer"}`,
+			pathValues: []string{"list", "1", "member", "bob"},
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "unknown role",
+			serve:      h.SetMember,
+			user:       "alice",
+			body:       `{"role":"admin"}`,
+			pathValues: []string{"list", "1", "member", "bob"},
+			wantCode:   http.StatusBadRequest,
+		},
+		{name: "members", serve: h.Members, user: "bob", pathValues: []string{"list", "1"}, wantCode: http.StatusOK},
+		{name: "add todo as owner", serve: h.Add, user: "alice", body: `{"item":"Milk"}`, pathValues: []string{"list", "1"}, wantCode: http.StatusCreated},
+		{name: "add todo as viewer", serve: h.Add, user: "bob", body: `{"item":"Eggs"}`, pathValues: []string{"list", "1"}, wantCode: http.StatusForbidden},
+		{name: "list todos as viewer", serve: h.ListTodos, user: "bob", pathValues: []string{"list", "1"}, wantCode: http.StatusOK},
+		{name: "get todo as viewer", serve: h.Get, user: "bob", pathValues: []string{"list", "1", "id", "1"}, wantCode: http.StatusOK},
+		{name: "delete list as viewer", serve: h.DeleteList, user: "bob", pathValues: []string{"list", "1"}, wantCode: http.StatusForbidden},
+		{name: "remove last owner", serve: h.RemoveMember, user: "alice", pathValues: []string{"list", "1", "member", "alice"}, wantCode: http.StatusConflict},
+		{name: "leave", serve: h.RemoveMember, user: "bob", pathValues: []string{"list", "1", "member", "bob"}, wantCode: http.StatusNoContent},
+		{name: "delete list", serve: h.DeleteList, user: "alice", pathValues: []string{"list", "1"}, wantCode: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(tt.serve, tt.user, tt.body, tt.pathValues...); w.Code != tt.wantCode {
+				t.Errorf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+		})
+	}
+
+	w = do(h.Lists, "alice", "")
+	var lists []db.MemberList
+	if err := json.NewDecoder(w.Body).Decode(&lists); err != nil {
+		t.Fatalf("failed to decode lists: %v", err)
+	}
+	if len(lists) != 0 {
+		t.Errorf("Lists after delete = %v, want none", lists)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// AddSubtask adds a todo as a subtask of the todo in the path.
+func (h *Handler) AddSubtask(resp http.ResponseWriter, req *http.Request) {
+	parentID, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	var todoItem TodoItem
+	if decodeErr := json.NewDecoder(req.Body).Decode(&todoItem); decodeErr != nil {
+		h.handleError(resp, req, invalidJSON(decodeErr))
+
+		return
+	}
+
+	item, err := svc.AddSubtask(req.Context(), parentID, todoItem.Item, todoItem.details())
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusCreated, item)
+}
+
+// Subtasks lists the subtasks of the todo in the path, nested down to the depth query parameter.
+// It lists the direct subtasks only by default.
+func (h *Handler) Subtasks(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	depth, err := intParam(req.URL.Query().Get("depth"), "depth")
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	subtasks, err := svc.Subtasks(req.Context(), id, depth)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, subtasks)
+}
@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestSubtasks(t *testing.T) {
+	h := newHandler(t, "plan trip")
+
+	tests := []struct {
+		name     string
+		id       string
+		body     string
+		wantCode int
+	}{
+		{name: "add subtask", id: "1", body: `{"item": "pack", "priority": "high"}`, wantCode: http.StatusCreated},
+		{name: "add nested subtask", id: "2", body: `{"item": "pack charger"}`, wantCode: http.StatusCreated},
+		{name: "unknown parent", id: "9", body: `{"item": "orphan"}`, wantCode: http.StatusNotFound},
+		{name: "invalid parent id", id: "x", body: `{"item": "orphan"}`, wantCode: http.StatusBadRequest},
+		{name: "empty item", id: "1", body: `{"item": ""}`, wantCode: http.StatusBadRequest},
+		{name: "invalid JSON", id: "1", body: `{`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/todo/"+tt.id+"/children", strings.NewReader(tt.body))
+			req.SetPathValue("id", tt.id)
+
+			w := httptest.NewRecorder()
+			h.AddSubtask(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+		})
+	}
+
+	listTests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantDepth int
+	}{
+		{name: "children", wantCode: http.StatusOK, wantDepth: 1},
+		{name: "tree", query: "?depth=5", wantCode: http.StatusOK, wantDepth: 2},
+		{name: "invalid depth", query: "?depth=deep", wantCode: http.StatusBadRequest},
+		{name: "depth out of range", query: "?depth=99", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/todo/1/children"+tt.query, nil)
+			req.SetPathValue("id", "1")
+
+			w := httptest.NewRecorder()
+			h.Subtasks(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var subtasks []service.Subtask
+			if err := json.NewDecoder(w.Body).Decode(&subtasks); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			depth := 0
+			for level := subtasks; len(level) > 0; level = level[0].Subtasks {
+				depth++
+			}
+			if depth != tt.wantDepth {
+				t.Errorf("expected %d levels of subtasks, got %d", tt.wantDepth, depth)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/1", nil)
+	req.SetPathValue("id", "1")
+
+	w := httptest.NewRecorder()
+	h.Get(w, req)
+
+	var parent db.Item
+	if err := json.NewDecoder(w.Body).Decode(&parent); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if parent.OpenSubtasks != 1 || parent.Completion == nil || *parent.Completion != 0 {
+		t.Errorf("expected 1 open subtask and 0%% completion, got %d and %v", parent.OpenSubtasks, parent.Completion)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// Trash lists a page of the caller's deleted todos, or of the deleted todos of the list in the
+// path. It accepts the query parameters of ListTodos.
+func (h *Handler) Trash(resp http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	page, err := svc.Trash(req.Context(), opts)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeJSON(resp, req, http.StatusOK, page)
+}
+
+// Restore moves a deleted todo out of the trash.
+func (h *Handler) Restore(resp http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	svc, err := h.service(req)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	item, err := svc.Restore(req.Context(), id)
+	if err != nil {
+		h.handleError(resp, req, err)
+
+		return
+	}
+
+	h.writeItem(resp, req, http.StatusOK, item)
+}
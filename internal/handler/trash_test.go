@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTrash(t *testing.T) {
+	h := newHandler(t, "call the bank", "water plants")
+
+	req := httptest.NewRequest(http.MethodDelete, "/todo/1", nil)
+	req.SetPathValue("id", "1")
+
+	w := httptest.NewRecorder()
+	h.Delete(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	listTests := []struct {
+		name      string
+		serve     http.HandlerFunc
+		query     string
+		wantCode  int
+		wantTasks []string
+	}{
+		{name: "trash", serve: h.Trash, wantCode: http.StatusOK, wantTasks: []string{"call the bank"}},
+		{name: "todos", serve: h.ListTodos, wantCode: http.StatusOK, wantTasks: []string{"water plants"}},
+		{name: "trash with invalid limit", serve: h.Trash, query: "?limit=x", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.serve(w, httptest.NewRequest(http.MethodGet, "/trash"+tt.query, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status code %d, got %d", tt.wantCode, w.Code)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var page service.ListPage
+			if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			tasks := []string{}
+			for _, item := range page.Items {
+				tasks = append(tasks, item.Task)
+			}
+			if len(tasks) != len(tt.wantTasks) || (len(tasks) > 0 && tasks[0] != tt.wantTasks[0]) {
+				t.Errorf("expected tasks %v, got %v", tt.wantTasks, tasks)
+			}
+		})
+	}
+
+	restoreTests := []struct {
+		name     string
+		id       string
+		wantCode int
+	}{
+		{name: "restore", id: "1", wantCode: http.StatusOK},
+		{name: "restore again", id: "1", wantCode: http.StatusNotFound},
+		{name: "not in trash", id: "2", wantCode: http.StatusNotFound},
+		{name: "invalid id", id: "x", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range restoreTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/todo/"+tt.id+"/restore", nil)
+			req.SetPathValue("id", tt.id)
+
+			w := httptest.NewRecorder()
+			h.Restore(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status code %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+		})
+	}
+}
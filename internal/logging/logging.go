@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/brkcnr/golandworks-api/internal/config"
+)
+
+// contextKey is the context key type for the request logger.
+type contextKey struct{}
+
+// New creates a logger that writes to w in the configured format, dropping records below the configured level.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+
+	if cfg.Format == config.LogFormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// NewContext returns a copy of ctx carrying the logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none.
+// Request handlers store a logger that already carries the request attributes.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return fallback
+}
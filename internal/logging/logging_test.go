@@ -0,0 +1,58 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.New(config.LogConfig{Level: slog.LevelInfo, Format: config.LogFormatJSON}, &buf)
+
+		logger.Debug("dropped")
+		logger.Info("kept", "id", 1)
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+		}
+
+		if record["msg"] != "kept" || record["id"] != float64(1) {
+			t.Errorf("unexpected record: %v", record)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.New(config.LogConfig{Level: slog.LevelDebug, Format: config.LogFormatText}, &buf)
+
+		logger.Debug("kept")
+
+		if !strings.Contains(buf.String(), "msg=kept") {
+			t.Errorf("expected a text record, got %q", buf.String())
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+	fallback := slog.Default()
+
+	if got := logging.FromContext(context.Background(), fallback); got != fallback {
+		t.Errorf("FromContext() = %v, want the fallback logger", got)
+	}
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	ctx := logging.NewContext(context.Background(), logger)
+
+	if got := logging.FromContext(ctx, fallback); got != logger {
+		t.Errorf("FromContext() = %v, want the stored logger", got)
+	}
+}
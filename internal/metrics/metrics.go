@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name.
+const namespace = "golandworks"
+
+// unmatchedRoute labels requests that matched no route, so unknown paths cannot
+// create unbounded label values.
+const unmatchedRoute = "unmatched"
+
+// countTimeout bounds the query behind the todo count metric on each scrape.
+const countTimeout = 5 * time.Second
+
+// Metrics holds the application metrics and the registry they are exposed from.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	dbErrors     *prometheus.CounterVec
+}
+
+// New creates the metrics and registers them, together with Go runtime and process metrics,
+// in a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route pattern and status code.",
+		}, []string{"route", "status"}),
+
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_operation_duration_seconds",
+			Help:      "Storer operation latency by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_operation_errors_total",
+			Help:      "Failed Storer operations by operation and error kind.",
+		}, []string{"operation", "kind"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbDuration,
+		m.dbErrors,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+// A collector that fails is left out of the scrape instead of failing it.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry: m.registry,
+
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveRequest records a completed HTTP request. An empty route means no route matched.
+func (m *Metrics) ObserveRequest(route string, status int, duration time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, code).Inc()
+	m.httpDuration.WithLabelValues(route, code).Observe(duration.Seconds())
+}
+
+// ObserveStore records the latency and errors of a Storer operation.
+// It has the signature of a db.Observer.
+func (m *Metrics) ObserveStore(ctx context.Context, op string) (context.Context, func(error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		m.dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			m.dbErrors.WithLabelValues(op, errorKind(err)).Inc()
+		}
+	}
+}
+
+// RegisterPool exposes connection pool statistics, read from stat on each scrape.
+func (m *Metrics) RegisterPool(stat func() *pgxpool.Stat) {
+	m.registry.MustRegister(&poolCollector{stat: stat})
+}
+
+// RegisterTodoCounts exposes the number of todos by status, read from count on each scrape.
+func (m *Metrics) RegisterTodoCounts(count func(ctx context.Context) (map[string]int64, error)) {
+	m.registry.MustRegister(&todoCollector{count: count})
+}
+
+// errorKind returns the apierror kind of err, or "internal" for errors without one.
+func errorKind(err error) string {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Kind != "" {
+		return string(apiErr.Kind)
+	}
+
+	return "internal"
+}
+
+// poolCollector collects pgxpool statistics.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+// Pool statistic descriptions.
+var (
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_db_pool_acquired_connections",
+		"Connections currently in use.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(namespace+"_db_pool_idle_connections",
+		"Idle connections in the pool.", nil, nil)
+	poolTotalDesc = prometheus.NewDesc(namespace+"_db_pool_total_connections",
+		"Total connections in the pool, including ones being established.", nil, nil)
+	poolMaxDesc = prometheus.NewDesc(namespace+"_db_pool_max_connections",
+		"Maximum size of the pool.", nil, nil)
+)
+
+// Describe implements prometheus.Collector.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+}
+
+// todoCollector collects the number of todos by status.
+type todoCollector struct {
+	count func(ctx context.Context) (map[string]int64, error)
+}
+
+// todoCountDesc describes the todo count metric.
+var todoCountDesc = prometheus.NewDesc(namespace+"_todo_items",
+	"Number of todo items by status.", []string{"status"}, nil)
+
+// Describe implements prometheus.Collector.
+func (c *todoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- todoCountDesc
+}
+
+// Collect implements prometheus.Collector. If counting fails the metric is reported as
+// invalid, so it is missing from the scrape instead of showing stale numbers.
+func (c *todoCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(todoCountDesc, err)
+
+		return
+	}
+
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(todoCountDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
@@ -0,0 +1,114 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/metrics"
+)
+
+// scrape returns the metrics exposition served by m.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   func(m *metrics.Metrics)
+		expected []string
+	}{
+		{
+			name: "request",
+			record: func(m *metrics.Metrics) {
+				m.ObserveRequest("GET /todo/{id}", http.StatusNotFound, 10*time.Millisecond)
+			},
+			expected: []string{
+				`golandworks_http_requests_total{route="GET /todo/{id}",status="404"} 1`,
+				`golandworks_http_request_duration_seconds_count{route="GET /todo/{id}",status="404"} 1`,
+			},
+		},
+		{
+			name: "unmatched request",
+			record: func(m *metrics.Metrics) {
+				m.ObserveRequest("", http.StatusNotFound, time.Millisecond)
+			},
+			expected: []string{`golandworks_http_requests_total{route="unmatched",status="404"} 1`},
+		},
+		{
+			name: "store operations",
+			record: func(m *metrics.Metrics) {
+				_, done := m.ObserveStore(context.Background(), "insert_item")
+				done(nil)
+
+				_, done = m.ObserveStore(context.Background(), "insert_item")
+				done(apierror.Wrap(apierror.ErrDuplicateTodo, http.StatusInternalServerError, "failed to add todo"))
+
+				_, done = m.ObserveStore(context.Background(), "get_item")
+				done(errors.New("connection reset"))
+			},
+			expected: []string{
+				`golandworks_db_operation_duration_seconds_count{operation="insert_item"} 2`,
+				`golandworks_db_operation_errors_total{kind="duplicate_todo",operation="insert_item"} 1`,
+				`golandworks_db_operation_errors_total{kind="internal",operation="get_item"} 1`,
+			},
+		},
+		{
+			name: "todo counts",
+			record: func(m *metrics.Metrics) {
+				m.RegisterTodoCounts(func(context.Context) (map[string]int64, error) {
+					return map[string]int64{"DONE": 2, "IN_PROGRESS": 1}, nil
+				})
+			},
+			expected: []string{
+				`golandworks_todo_items{status="DONE"} 2`,
+				`golandworks_todo_items{status="IN_PROGRESS"} 1`,
+			},
+		},
+		{
+			name: "failing todo counts do not fail the scrape",
+			record: func(m *metrics.Metrics) {
+				m.RegisterTodoCounts(func(context.Context) (map[string]int64, error) {
+					return nil, errors.New("connection refused")
+				})
+				m.ObserveRequest("GET /todo", http.StatusOK, time.Millisecond)
+			},
+			expected: []string{`golandworks_http_requests_total{route="GET /todo",status="200"} 1`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.New()
+			tt.record(m)
+
+			body := scrape(t, m)
+			for _, expected := range tt.expected {
+				if !strings.Contains(body, expected) {
+					t.Errorf("expected metrics to contain %q", expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// Header is the HTTP header that carries the request ID.
+const Header = "X-Request-ID"
+
+// contextKey is the context key type for the request ID.
+type contextKey struct{}
+
+// fallbackCount keeps IDs unique when they cannot be random.
+var fallbackCount atomic.Uint64
+
+// New generates a new random request ID. If no randomness is available, the ID is built from
+// the current time and a counter instead, which is still unique within the process.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b, uint64(time.Now().UnixNano()))
+		binary.BigEndian.PutUint64(b[8:], fallbackCount.Add(1))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a copy of ctx carrying the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
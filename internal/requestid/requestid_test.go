@@ -0,0 +1,33 @@
+package requestid_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/requestid"
+)
+
+func TestNew(t *testing.T) {
+	first, second := requestid.New(), requestid.New()
+
+	if len(first) != 32 {
+		t.Errorf("New() = %q, want 32 hex characters", first)
+	}
+
+	if first == second {
+		t.Errorf("New() returned %q twice", first)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+
+	if got := requestid.FromContext(ctx); got != "" {
+		t.Errorf("FromContext() = %q, want empty", got)
+	}
+
+	ctx = requestid.NewContext(ctx, "abc")
+	if got := requestid.FromContext(ctx); got != "abc" {
+		t.Errorf("FromContext() = %q, want %q", got, "abc")
+	}
+}
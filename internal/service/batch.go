@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Batch operations.
+const (
+	BatchCreate     = "create"
+	BatchUpdate     = "update"
+	BatchTransition = "transition"
+	BatchDelete     = "delete"
+)
+
+// MaxBatchSize is the most operations a batch may hold.
+const MaxBatchSize = 1000
+
+// BatchOp is one operation of a batch.
+type BatchOp struct {
+	// Op is BatchCreate, BatchUpdate, BatchTransition or BatchDelete.
+	Op string
+
+	// ID is the todo to update, transition or delete.
+	ID int64
+
+	// Version is the version the todo must be at to be changed, or zero for any version.
+	Version int64
+
+	// Task and Details describe the todo to create.
+	Task    string
+	Details ItemDetails
+
+	// Patch holds the fields to update.
+	Patch ItemPatch
+
+	// Status is the status to transition to.
+	Status string
+}
+
+// BatchResult is the outcome of one operation of a batch.
+type BatchResult struct {
+	// Item is the todo as stored, or nil for deletes and failed operations.
+	Item *db.Item
+
+	// Err is why the operation failed, or nil if it succeeded.
+	Err error
+}
+
+// Batch applies the operations in order in one transaction, with the same checks as Add, Patch,
+// Transition and Delete, and returns the outcome of each. If atomic is set consecutive creates
+// are inserted together and the first failing operation rolls the whole batch back and its
+// error is returned; otherwise failed operations are left out and the others are kept.
+func (s *TodoService) Batch(ctx context.Context, ops []BatchOp, atomic bool) (_ []BatchResult, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Batch", attribute.Int("batch.size", len(ops)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return nil, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("a batch must hold between 1 and %d operations", MaxBatchSize),
+		).WithDetails(apierror.FieldError{Field: "ops", Message: fmt.Sprintf("must hold between 1 and %d operations", MaxBatchSize)})
+	}
+
+	results := make([]BatchResult, len(ops))
+	err = s.db.InTx(ctx, func(tx db.Storer) error {
+		batch := s.withStore(tx)
+		for i := 0; i < len(ops); {
+			if ops[i].Op == BatchCreate {
+				end := i + 1
+				for end < len(ops) && ops[end].Op == BatchCreate {
+					end++
+				}
+
+				if err := batch.batchCreate(ctx, i, ops[i:end], results[i:end], atomic); err != nil {
+					return err
+				}
+				i = end
+
+				continue
+			}
+
+			if atomic {
+				item, err := batch.batchOp(ctx, ops[i])
+				if err != nil {
+					return batchError(i, err)
+				}
+				results[i].Item = item
+			} else {
+				// A savepoint undoes whatever the failed operation changed before it failed.
+				results[i].Err = tx.InTx(ctx, func(opTx db.Storer) error {
+					var opErr error
+					results[i].Item, opErr = batch.withStore(opTx).batchOp(ctx, ops[i])
+
+					return opErr
+				})
+			}
+			i++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	s.log(ctx).InfoContext(ctx, "todo batch applied", "ops", len(ops), "failed", failed)
+
+	return results, nil
+}
+
+// batchCreate inserts the todos of a run of create operations starting at index first of the
+// batch, and records their outcome in results. An atomic batch inserts them together; otherwise
+// each gets a savepoint, so one that fails leaves the others in.
+func (s *TodoService) batchCreate(ctx context.Context, first int, ops []BatchOp, results []BatchResult, atomic bool) error {
+	scope, err := s.scope(ctx, RoleEditor)
+	if err != nil {
+		if atomic {
+			return batchError(first, err)
+		}
+
+		for i := range results {
+			results[i].Err = err
+		}
+
+		return nil
+	}
+
+	if !atomic {
+		for i, op := range ops {
+			results[i].Err = s.db.InTx(ctx, func(tx db.Storer) error {
+				item, opErr := s.withStore(tx).add(ctx, scope, 0, op.Task, op.Details)
+				if opErr != nil {
+					return opErr
+				}
+				results[i].Item = &item
+
+				return nil
+			})
+		}
+
+		return nil
+	}
+
+	items := make([]db.Item, len(ops))
+	for i, op := range ops {
+		if items[i], err = newItem(ctx, scope, 0, op.Task, op.Details); err != nil {
+			return batchError(first+i, err)
+		}
+	}
+
+	stored, err := s.db.InsertItems(audited(ctx, db.ActionCreate), items)
+	if err != nil {
+		if len(stored) == len(items) {
+			// No single todo failed, so the batch cannot go on without them.
+			return apierror.Wrap(err, http.StatusInternalServerError, "failed to add todos")
+		}
+
+		return batchError(first+len(stored), apierror.Wrap(err, http.StatusInternalServerError, "failed to add todo"))
+	}
+
+	for i := range stored {
+		results[i].Item = &stored[i]
+		s.log(ctx).InfoContext(ctx, "todo created", "todo_id", stored[i].ID)
+	}
+
+	return nil
+}
+
+// batchOp applies an operation other than a create and returns the todo it changed, or nil if it
+// deleted the todo.
+func (s *TodoService) batchOp(ctx context.Context, op BatchOp) (*db.Item, error) {
+	svc := s
+	if op.Version != 0 {
+		svc = s.IfMatch(op.Version)
+	}
+
+	var item db.Item
+	var err error
+	switch op.Op {
+	case BatchUpdate:
+		item, err = svc.Patch(ctx, op.ID, op.Patch)
+	case BatchTransition:
+		item, err = svc.Transition(ctx, op.ID, op.Status)
+	case BatchDelete:
+		return nil, svc.Delete(ctx, op.ID)
+	default:
+		return nil, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("unknown batch operation %q", op.Op),
+		).WithDetails(apierror.FieldError{Field: "op", Message: "must be create, update, transition or delete"})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// batchError returns the error for the failed operation i of an atomic batch. It keeps the
+// status and kind of err, and names the operation in its details.
+func batchError(i int, err error) error {
+	resp := apierror.Response(err, "")
+
+	return apierror.Wrap(err, resp.Code, fmt.Sprintf("batch operation %d failed", i)).
+		WithDetails(apierror.FieldError{Field: fmt.Sprintf("ops[%d]", i), Message: resp.Message})
+}
+
+// withStore returns a service that uses store, such as a transaction, instead of its own.
+func (s *TodoService) withStore(store db.Storer) *TodoService {
+	stored := *s
+	stored.db = store
+
+	return &stored
+}
@@ -0,0 +1,178 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTodoService_Batch(t *testing.T) {
+	alice := as("alice")
+	notes := "white"
+
+	tests := []struct {
+		name     string
+		ops      []service.BatchOp
+		atomic   bool
+		wantErr  error
+		wantErrs []error
+		wantLeft []string
+	}{
+		{
+			name: "all operations succeed",
+			ops: []service.BatchOp{
+				{Op: service.BatchCreate, Task: "mow the lawn"},
+				{Op: service.BatchCreate, Task: "rake leaves", Details: service.ItemDetails{Tags: []string{"Garden"}}},
+				{Op: service.BatchUpdate, ID: 1, Version: 1, Patch: service.ItemPatch{Notes: &notes}},
+				{Op: service.BatchTransition, ID: 1, Status: string(service.StatusInProgress)},
+				{Op: service.BatchDelete, ID: 2},
+				{Op: service.BatchCreate, Task: "wash the car"},
+			},
+			atomic:   true,
+			wantErrs: make([]error, 6),
+			wantLeft: []string{"paint the fence", "mow the lawn", "rake leaves", "wash the car"},
+		},
+		{
+			name: "atomic batch rolls back",
+			ops: []service.BatchOp{
+				{Op: service.BatchCreate, Task: "mow the lawn"},
+				{Op: service.BatchDelete, ID: 2},
+				{Op: service.BatchTransition, ID: 1, Status: string(service.StatusDone)},
+			},
+			atomic:   true,
+			wantErr:  apierror.ErrInvalidTransition,
+			wantLeft: []string{"paint the fence", "buy paint"},
+		},
+		{
+			name: "atomic batch rolls back creates",
+			ops: []service.BatchOp{
+				{Op: service.BatchCreate, Task: "mow the lawn"},
+				{Op: service.BatchCreate, Task: "buy paint"},
+			},
+			atomic:   true,
+			wantErr:  apierror.ErrDuplicateTodo,
+			wantLeft: []string{"paint the fence", "buy paint"},
+		},
+		{
+			name: "best effort batch keeps what succeeds",
+			ops: []service.BatchOp{
+				{Op: service.BatchCreate, Task: "mow the lawn"},
+				{Op: service.BatchCreate, Task: "buy paint"},
+				{Op: service.BatchCreate, Task: ""},
+				{Op: service.BatchCreate, Task: "rake leaves"},
+				{Op: service.BatchUpdate, ID: 1, Version: 7, Patch: service.ItemPatch{Notes: &notes}},
+				{Op: service.BatchDelete, ID: 2},
+				{Op: "archive", ID: 1},
+			},
+			wantErrs: []error{
+				nil,
+				apierror.ErrDuplicateTodo,
+				apierror.ErrInvalidRequest,
+				nil,
+				apierror.ErrPreconditionFailed,
+				nil,
+				apierror.ErrInvalidRequest,
+			},
+			wantLeft: []string{"paint the fence", "mow the lawn", "rake leaves"},
+		},
+		{
+			name:    "empty batch",
+			atomic:  true,
+			wantErr: apierror.ErrInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.New(service.WithDB(db.NewMemory()))
+			for _, task := range []string{"paint the fence", "buy paint"} {
+				if _, err := svc.Add(alice, task, service.ItemDetails{}); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+
+			results, err := svc.Batch(alice, tt.ops, tt.atomic)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Batch() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Batch() error = %v", err)
+			}
+
+			if err == nil && len(results) != len(tt.wantErrs) {
+				t.Fatalf("Batch() returned %d results, want %d", len(results), len(tt.wantErrs))
+			}
+
+			for i, result := range results {
+				if tt.wantErrs[i] == nil {
+					if result.Err != nil {
+						t.Errorf("Batch() result %d error = %v", i, result.Err)
+					}
+				} else if !errors.Is(result.Err, tt.wantErrs[i]) {
+					t.Errorf("Batch() result %d error = %v, want %v", i, result.Err, tt.wantErrs[i])
+				}
+
+				if wantItem := result.Err == nil && tt.ops[i].Op != service.BatchDelete; (result.Item != nil) != wantItem {
+					t.Errorf("Batch() result %d item = %+v, want one: %t", i, result.Item, wantItem)
+				}
+			}
+
+			if tt.wantLeft == nil {
+				return
+			}
+
+			page, err := svc.ListTodos(alice, service.ListOptions{})
+			if err != nil {
+				t.Fatalf("ListTodos() error = %v", err)
+			}
+
+			left := map[string]bool{}
+			for _, item := range page.Items {
+				left[item.Task] = true
+			}
+			if len(page.Items) != len(tt.wantLeft) {
+				t.Errorf("ListTodos() = %+v, want %v", page.Items, tt.wantLeft)
+			}
+			for _, task := range tt.wantLeft {
+				if !left[task] {
+					t.Errorf("ListTodos() is missing %q", task)
+				}
+			}
+		})
+	}
+}
+
+func TestTodoService_BatchList(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	alice, carol := as("alice"), as("carol")
+
+	list, err := svc.CreateList(alice, "Chores")
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "carol", "viewer"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+
+	shared := svc.ForList(list.ID)
+	ops := []service.BatchOp{{Op: service.BatchCreate, Task: "clean the gutters"}}
+
+	results, err := shared.Batch(carol, ops, false)
+	if err != nil || !errors.Is(results[0].Err, apierror.ErrForbidden) {
+		t.Errorf("Batch() by viewer = %+v, %v, want %v for the create", results, err, apierror.ErrForbidden)
+	}
+
+	results, err = shared.Batch(alice, ops, true)
+	if err != nil || results[0].Item.ListID != list.ID {
+		t.Errorf("Batch() = %+v, %v, want the todo in the list", results, err)
+	}
+
+	history, err := shared.History(carol, results[0].Item.ID)
+	if err != nil || len(history) != 1 || history[0].Actor != "alice" || history[0].Action != db.ActionCreate {
+		t.Errorf("History() = %+v, %v, want the create by alice", history, err)
+	}
+}
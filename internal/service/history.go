@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// History returns the change history of a todo item of the caller by its ID, oldest first.
+// Items in the trash keep their history until they are purged.
+func (s *TodoService) History(ctx context.Context, id int64) (_ []db.Event, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.History", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := s.scope(ctx, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.db.ListEvents(ctx, scope, id)
+}
+
+// AllHistory returns the change history of a todo item of any owner or list by its ID, oldest
+// first, including items that were purged or deleted with their list. It is meant for
+// administrators; callers must check access.
+func (s *TodoService) AllHistory(ctx context.Context, id int64) (_ []db.Event, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.AllHistory", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.db.ListAllEvents(ctx, id)
+}
+
+// audited returns a context whose changes the store records as action by the caller.
+func audited(ctx context.Context, action string) context.Context {
+	return db.WithAudit(ctx, db.Audit{Actor: ownerID(ctx), Action: action})
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTodoService_History(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	alice, bob, carol, dave := as("alice"), as("bob"), as("carol"), as("dave")
+
+	list, err := svc.CreateList(alice, "Chores")
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "bob", "editor"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "carol", "viewer"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+
+	shared := svc.ForList(list.ID)
+
+	item, err := shared.Add(bob, "Clean the gutters", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	priority := "high"
+	if _, err = shared.Patch(alice, item.ID, service.ItemPatch{Priority: &priority}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if _, err = shared.Transition(alice, item.ID, string(service.StatusInProgress)); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	// Rejected changes are not recorded.
+	if _, err = shared.Transition(carol, item.ID, string(service.StatusDone)); !errors.Is(err, apierror.ErrForbidden) {
+		t.Fatalf("Transition() by viewer error = %v, want %v", err, apierror.ErrForbidden)
+	}
+
+	if err = shared.Delete(bob, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err = shared.Restore(alice, item.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	events, err := shared.History(carol, item.ID)
+	if err != nil {
+		t.Fatalf("History() by viewer error = %v", err)
+	}
+
+	want := []struct{ actor, action string }{
+		{"bob", db.ActionCreate},
+		{"alice", db.ActionUpdate},
+		{"alice", db.ActionTransition},
+		{"bob", db.ActionDelete},
+		{"alice", db.ActionRestore},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("History() = %+v, want %d events", events, len(want))
+	}
+
+	for i, w := range want {
+		if events[i].Actor != w.actor || events[i].Action != w.action {
+			t.Errorf("History()[%d] = %s by %q, want %s by %q", i, events[i].Action, events[i].Actor, w.action, w.actor)
+		}
+	}
+
+	if got := events[1].Changes["priority"]; got.Before != "normal" || got.After != "high" {
+		t.Errorf("History()[1] priority = %+v, want normal to high", got)
+	}
+	if got := events[2].Changes["status"]; len(events[2].Changes) != 1 || got.Before != string(service.StatusToBeStarted) ||
+		got.After != string(service.StatusInProgress) {
+		t.Errorf("History()[2] changes = %+v, want only the status", events[2].Changes)
+	}
+
+	tests := []struct {
+		name    string
+		svc     *service.TodoService
+		wantErr error
+	}{
+		{name: "non-member", svc: shared, wantErr: apierror.ErrNotFound},
+		{name: "outside the list", svc: svc, wantErr: apierror.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.svc.History(dave, item.ID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("History() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
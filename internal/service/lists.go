@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreateList creates a list with the caller as its owner.
+func (s *TodoService) CreateList(ctx context.Context, name string) (_ db.List, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.CreateList")
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return db.List{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			"list name cannot be empty",
+		).WithDetails(apierror.FieldError{Field: "name", Message: "must not be empty"})
+	}
+
+	list, err := s.db.InsertList(ctx, db.List{Name: name}, ownerID(ctx))
+	if err != nil {
+		return db.List{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to create list")
+	}
+
+	s.log(ctx).InfoContext(ctx, "list created", "list_id", list.ID)
+
+	return list, nil
+}
+
+// Lists returns the lists the caller is a member of, with the caller's role.
+func (s *TodoService) Lists(ctx context.Context) (_ []db.MemberList, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Lists")
+	defer func() { tracing.End(span, err) }()
+
+	lists, err := s.db.ListLists(ctx, ownerID(ctx))
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to get lists")
+	}
+
+	return lists, nil
+}
+
+// GetList returns a list of which the caller is a member, with the caller's role.
+func (s *TodoService) GetList(ctx context.Context, listID int64) (_ db.MemberList, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.GetList", attribute.Int64("list.id", listID))
+	defer func() { tracing.End(span, err) }()
+
+	member, err := s.member(ctx, listID, RoleViewer)
+	if err != nil {
+		return db.MemberList{}, err
+	}
+
+	list, err := s.db.GetList(ctx, listID)
+	if err != nil {
+		return db.MemberList{}, err
+	}
+
+	return db.MemberList{List: list, Role: member.Role}, nil
+}
+
+// DeleteList deletes a list with its todos. Only owners may delete a list.
+func (s *TodoService) DeleteList(ctx context.Context, listID int64) (err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.DeleteList", attribute.Int64("list.id", listID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = s.member(ctx, listID, RoleOwner); err != nil {
+		return err
+	}
+
+	if err = s.db.DeleteList(audited(ctx, db.ActionPurge), listID); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete list")
+	}
+
+	s.log(ctx).InfoContext(ctx, "list deleted", "list_id", listID)
+
+	return nil
+}
+
+// Members returns the members of a list of which the caller is a member.
+func (s *TodoService) Members(ctx context.Context, listID int64) (_ []db.Member, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Members", attribute.Int64("list.id", listID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = s.member(ctx, listID, RoleViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := s.db.ListMembers(ctx, listID)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to get list members")
+	}
+
+	return members, nil
+}
+
+// SetMember adds a member to a list or changes their role. Only owners may manage members,
+// and the last owner cannot be demoted.
+func (s *TodoService) SetMember(ctx context.Context, listID int64, memberID, role string) (_ db.Member, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.SetMember", attribute.Int64("list.id", listID))
+	defer func() { tracing.End(span, err) }()
+
+	if strings.TrimSpace(memberID) == "" {
+		return db.Member{}, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "member cannot be empty")
+	}
+
+	newRole, err := ParseRole(role)
+	if err != nil {
+		return db.Member{}, err
+	}
+
+	if _, err = s.member(ctx, listID, RoleOwner); err != nil {
+		return db.Member{}, err
+	}
+
+	if newRole != RoleOwner {
+		if err = s.keepOwner(ctx, listID, memberID); err != nil {
+			return db.Member{}, err
+		}
+	}
+
+	member, err := s.db.PutMember(ctx, db.Member{ListID: listID, MemberID: memberID, Role: string(newRole)})
+	if err != nil {
+		return db.Member{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to set list member")
+	}
+
+	s.log(ctx).InfoContext(ctx, "list member set", "list_id", listID, "member", memberID, "role", member.Role)
+
+	return member, nil
+}
+
+// RemoveMember removes a member from a list. Owners may remove anyone and members may
+// remove themselves, but the last owner cannot be removed.
+func (s *TodoService) RemoveMember(ctx context.Context, listID int64, memberID string) (err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.RemoveMember", attribute.Int64("list.id", listID))
+	defer func() { tracing.End(span, err) }()
+
+	need := RoleOwner
+	if memberID == ownerID(ctx) {
+		need = RoleViewer
+	}
+
+	if _, err = s.member(ctx, listID, need); err != nil {
+		return err
+	}
+
+	if err = s.keepOwner(ctx, listID, memberID); err != nil {
+		return err
+	}
+
+	if err = s.db.DeleteMember(ctx, listID, memberID); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to remove list member")
+	}
+
+	s.log(ctx).InfoContext(ctx, "list member removed", "list_id", listID, "member", memberID)
+
+	return nil
+}
+
+// keepOwner returns ErrLastOwner if memberID is the only owner of the list.
+func (s *TodoService) keepOwner(ctx context.Context, listID int64, memberID string) error {
+	members, err := s.db.ListMembers(ctx, listID)
+	if err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to get list members")
+	}
+
+	var owners int
+	isOwner := false
+	for _, member := range members {
+		if Role(member.Role) == RoleOwner {
+			owners++
+			isOwner = isOwner || member.MemberID == memberID
+		}
+	}
+
+	if isOwner && owners == 1 {
+		return apierror.Wrap(apierror.ErrLastOwner, http.StatusConflict, "cannot remove or demote the last owner of a list")
+	}
+
+	return nil
+}
+
+// member returns the caller's membership of a list, checking that their role allows need.
+// Non-members get a not found error, members with a lesser role a forbidden error.
+func (s *TodoService) member(ctx context.Context, listID int64, need Role) (db.Member, error) {
+	member, err := s.db.GetMember(ctx, listID, ownerID(ctx))
+	if err != nil {
+		if errors.Is(err, apierror.ErrNotFound) {
+			return db.Member{}, apierror.Wrap(apierror.ErrNotFound, http.StatusNotFound, "list not found")
+		}
+
+		return db.Member{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to get list member")
+	}
+
+	if !Role(member.Role).Allows(need) {
+		return db.Member{}, apierror.Wrap(
+			apierror.ErrForbidden,
+			http.StatusForbidden,
+			"this action requires the "+string(need)+" role on the list",
+		)
+	}
+
+	return member, nil
+}
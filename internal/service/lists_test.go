@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+// as returns a context authenticated as subject.
+func as(subject string) context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{Subject: subject})
+}
+
+func TestTodoService_Lists(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	alice, bob, carol, dave := as("alice"), as("bob"), as("carol"), as("dave")
+
+	if _, err := svc.CreateList(alice, "  "); !errors.Is(err, apierror.ErrInvalidRequest) {
+		t.Errorf("CreateList() with blank name error = %v, want %v", err, apierror.ErrInvalidRequest)
+	}
+
+	list, err := svc.CreateList(alice, "Groceries")
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+
+	if _, err = svc.SetMember(alice, list.ID, "bob", "editor"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "carol", "viewer"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "carol", "admin"); !errors.Is(err, apierror.ErrInvalidRole) {
+		t.Errorf("SetMember() with unknown role error = %v, want %v", err, apierror.ErrInvalidRole)
+	}
+
+	lists, err := svc.Lists(bob)
+	if err != nil || len(lists) != 1 || lists[0].Role != "editor" {
+		t.Errorf("Lists() = %v, %v, want the list as editor", lists, err)
+	}
+
+	shared := svc.ForList(list.ID)
+
+	item, err := shared.Add(bob, "Milk", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() by editor error = %v", err)
+	}
+	if item.ListID != list.ID || item.OwnerID != "bob" {
+		t.Errorf("Add() = %+v, want list %d created by bob", item, list.ID)
+	}
+
+	// The list item is not one of bob's personal todos.
+	if _, err = svc.Get(bob, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() of a list todo outside the list error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{
+			name:    "viewer reads",
+			call:    func() error { _, err := shared.Get(carol, item.ID); return err },
+			wantErr: nil,
+		},
+		{
+			name:    "viewer lists",
+			call:    func() error { _, err := shared.ListTodos(carol, service.ListOptions{}); return err },
+			wantErr: nil,
+		},
+		{
+			name:    "viewer cannot add",
+			call:    func() error { _, err := shared.Add(carol, "Eggs", service.ItemDetails{}); return err },
+			wantErr: apierror.ErrForbidden,
+		},
+		{
+			name:    "viewer cannot transition",
+			call:    func() error { _, err := shared.Transition(carol, item.ID, "IN_PROGRESS"); return err },
+			wantErr: apierror.ErrForbidden,
+		},
+		{
+			name: "editor updates",
+			call: func() error {
+				_, err := shared.Update(bob, item.ID, "Oat milk", "IN_PROGRESS", service.ItemDetails{})
+				return err
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "non-member cannot read",
+			call:    func() error { _, err := shared.Get(dave, item.ID); return err },
+			wantErr: apierror.ErrNotFound,
+		},
+		{
+			name:    "non-member cannot see the list",
+			call:    func() error { _, err := svc.GetList(dave, list.ID); return err },
+			wantErr: apierror.ErrNotFound,
+		},
+		{
+			name:    "editor cannot manage members",
+			call:    func() error { _, err := svc.SetMember(bob, list.ID, "dave", "viewer"); return err },
+			wantErr: apierror.ErrForbidden,
+		},
+		{
+			name:    "editor cannot delete the list",
+			call:    func() error { return svc.DeleteList(bob, list.ID) },
+			wantErr: apierror.ErrForbidden,
+		},
+		{
+			name:    "last owner cannot be demoted",
+			call:    func() error { _, err := svc.SetMember(alice, list.ID, "alice", "editor"); return err },
+			wantErr: apierror.ErrLastOwner,
+		},
+		{
+			name:    "last owner cannot leave",
+			call:    func() error { return svc.RemoveMember(alice, list.ID, "alice") },
+			wantErr: apierror.ErrLastOwner,
+		},
+		{
+			name:    "viewer cannot remove others",
+			call:    func() error { return svc.RemoveMember(carol, list.ID, "bob") },
+			wantErr: apierror.ErrForbidden,
+		},
+		{
+			name:    "viewer leaves",
+			call:    func() error { return svc.RemoveMember(carol, list.ID, "carol") },
+			wantErr: nil,
+		},
+		{
+			name:    "former viewer cannot read",
+			call:    func() error { _, err := shared.Get(carol, item.ID); return err },
+			wantErr: apierror.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// With a second owner, the first may step down.
+	if _, err = svc.SetMember(alice, list.ID, "bob", "owner"); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	if _, err = svc.SetMember(alice, list.ID, "alice", "viewer"); err != nil {
+		t.Errorf("SetMember() demoting one of two owners error = %v", err)
+	}
+
+	if err = svc.DeleteList(bob, list.ID); err != nil {
+		t.Fatalf("DeleteList() error = %v", err)
+	}
+	if _, err = shared.Get(bob, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() after DeleteList() error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestTodoService_Lists_StoreError(t *testing.T) {
+	svc := service.New(service.WithDB(failingDB{err: errors.New("connection lost")}))
+
+	if _, err := svc.CreateList(as("alice"), "Groceries"); err == nil {
+		t.Error("CreateList() error = nil, want error")
+	}
+
+	if _, err := svc.ForList(1).Get(as("alice"), 1); errors.Is(err, apierror.ErrNotFound) || err == nil {
+		t.Errorf("Get() error = %v, want an internal error", err)
+	}
+}
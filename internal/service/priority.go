@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+)
+
+// Priority is the urgency of a todo item.
+type Priority string
+
+// Todo item priorities, from least to most urgent.
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorities lists the valid priorities.
+var priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// ParsePriority validates and converts a string to a Priority. The empty string is PriorityNormal.
+func ParsePriority(s string) (Priority, error) {
+	if s == "" {
+		return PriorityNormal, nil
+	}
+
+	priority := Priority(s)
+	if !slices.Contains(priorities, priority) {
+		return "", apierror.Wrap(
+			apierror.ErrInvalidPriority,
+			http.StatusBadRequest,
+			fmt.Sprintf("unknown todo priority %q", s),
+		).WithDetails(apierror.FieldError{Field: "priority", Message: "must be low, normal, high or urgent"})
+	}
+
+	return priority, nil
+}
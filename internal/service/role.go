@@ -0,0 +1,49 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+)
+
+// Role is the role of a member of a list.
+type Role string
+
+// List member roles, from least to most privileged.
+const (
+	// RoleViewer can read the list and its todos.
+	RoleViewer Role = "viewer"
+
+	// RoleEditor can also create, change and delete todos of the list.
+	RoleEditor Role = "editor"
+
+	// RoleOwner can also manage members and delete the list.
+	RoleOwner Role = "owner"
+)
+
+// roleRanks orders the roles by privilege.
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ParseRole validates and converts a string to a Role.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", apierror.Wrap(
+			apierror.ErrInvalidRole,
+			http.StatusBadRequest,
+			fmt.Sprintf("unknown list role %q", s),
+		).WithDetails(apierror.FieldError{Field: "role", Message: "must be viewer, editor or owner"})
+	}
+
+	return role, nil
+}
+
+// Allows reports whether the role grants everything need grants.
+func (r Role) Allows(need Role) bool {
+	return roleRanks[r] >= roleRanks[need]
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    service.Role
+		wantErr bool
+	}{
+		{input: "viewer", want: service.RoleViewer},
+		{input: "editor", want: service.RoleEditor},
+		{input: "owner", want: service.RoleOwner},
+		{input: "admin", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := service.ParseRole(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, apierror.ErrInvalidRole) {
+					t.Errorf("ParseRole() error = %v, want %v", err, apierror.ErrInvalidRole)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("ParseRole() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRole_Allows(t *testing.T) {
+	tests := []struct {
+		role service.Role
+		need service.Role
+		want bool
+	}{
+		{role: service.RoleViewer, need: service.RoleViewer, want: true},
+		{role: service.RoleViewer, need: service.RoleEditor, want: false},
+		{role: service.RoleEditor, need: service.RoleViewer, want: true},
+		{role: service.RoleEditor, need: service.RoleOwner, want: false},
+		{role: service.RoleOwner, need: service.RoleEditor, want: true},
+		{role: service.Role("unknown"), need: service.RoleViewer, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.need), func(t *testing.T) {
+			if got := tt.role.Allows(tt.need); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,16 +2,102 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
 	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// Page size limits for paginated queries.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// MaxNotesLength is the maximum length of todo notes in bytes.
+const MaxNotesLength = 4096
+
+// cursorPrefix versions the cursor format, so a future format rejects old cursors instead of misreading them.
+const cursorPrefix = "o1:"
+
+// ListOptions filters, sorts and paginates ListTodos.
+type ListOptions struct {
+	// Statuses keeps only todos with one of the statuses; empty means any status.
+	Statuses []string
+
+	// Priorities keeps only todos with one of the priorities; empty means any priority.
+	Priorities []string
+
+	// Overdue keeps only todos that are past their due date and neither done nor cancelled.
+	Overdue bool
+
+	// DueBefore and DueAfter keep only todos due before, or at or after, the time.
+	// Zero times are ignored.
+	DueBefore time.Time
+	DueAfter  time.Time
+
+	// Tags keeps only todos with any of the tags, or with all of them if AllTags is set.
+	Tags    []string
+	AllTags bool
+
+	// Sort is created_at, task, status, due_at or priority, prefixed with - for descending order.
+	// Empty sorts by created_at. Todos without a due date sort last by due_at.
+	Sort string
+
+	// Limit is the page size; zero uses DefaultPageSize.
+	Limit int
+
+	// Cursor is the NextCursor of the previous page. It cannot be combined with Offset.
+	Cursor string
+
+	Offset int
+}
+
+// ListPage is one page of todo items.
+type ListPage struct {
+	Items []db.Item `json:"items"`
+
+	// NextCursor fetches the next page, or is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Results []db.SearchResult `json:"results"`
+
+	// NextOffset is the offset of the next page, or nil on the last page.
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
 // TodoService handles todo business logic.
 type TodoService struct {
-	db db.Storer
+	db     db.Storer
+	logger *slog.Logger
+	now    func() time.Time
+
+	// strictSubtasks keeps todos from being marked DONE while they have open subtasks.
+	strictSubtasks bool
+
+	// trashRetention is how long deleted todos stay in the trash; zero keeps them forever.
+	trashRetention time.Duration
+
+	// listID scopes the todo methods to a list; zero means the caller's personal todos.
+	listID int64
+
+	// ifMatch lists the versions a todo must be at to be changed; nil means any version.
+	ifMatch []int64
 }
 
 // Option is a function that configures a TodoService.
@@ -24,9 +110,39 @@ func WithDB(store db.Storer) Option {
 	}
 }
 
+// WithLogger sets the logger used when the context carries no request logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *TodoService) {
+		s.logger = logger
+	}
+}
+
+// WithClock sets the function that returns the current time, which decides what is overdue.
+func WithClock(now func() time.Time) Option {
+	return func(s *TodoService) {
+		s.now = now
+	}
+}
+
+// WithStrictSubtasks sets whether todos with open subtasks may be marked DONE. It is on by
+// default: a todo is only done once each of its subtasks is done or cancelled.
+func WithStrictSubtasks(strict bool) Option {
+	return func(s *TodoService) {
+		s.strictSubtasks = strict
+	}
+}
+
 // New creates a new TodoService with the given options.
 func New(opts ...Option) *TodoService {
-	svc := &TodoService{}
+	svc := &TodoService{
+		logger: slog.Default(),
+
+		now: time.Now,
+
+		strictSubtasks: true,
+
+		trashRetention: DefaultTrashRetention,
+	}
 	for _, opt := range opts {
 		opt(svc)
 	}
@@ -34,61 +150,663 @@ func New(opts ...Option) *TodoService {
 	return svc
 }
 
-// Add creates a new todo item.
-func (s *TodoService) Add(ctx context.Context, todo string) error {
+// ForList returns a service whose todo methods act on the todos of a list instead of the
+// caller's personal todos. Callers must be members of the list: viewers may read, editors
+// and owners may also write.
+func (s *TodoService) ForList(listID int64) *TodoService {
+	scoped := *s
+	scoped.listID = listID
+
+	return &scoped
+}
+
+// IfMatch returns a service whose Update, Patch, Transition and Delete only change a todo at
+// one of the versions, and fail with apierror.ErrPreconditionFailed otherwise. No versions
+// match no todo.
+func (s *TodoService) IfMatch(versions ...int64) *TodoService {
+	matched := *s
+	matched.ifMatch = append([]int64{}, versions...)
+
+	return &matched
+}
+
+// precondition checks that item is at a version the service may change, and returns the version
+// the store must still find it at, so that changes checked against item are not applied on top of
+// a concurrent one.
+func (s *TodoService) precondition(item db.Item) (int64, error) {
+	if s.ifMatch != nil && !slices.Contains(s.ifMatch, item.Version) {
+		return 0, apierror.ErrPreconditionFailed
+	}
+
+	return item.Version, nil
+}
+
+// ItemDetails holds the optional fields of a todo item.
+type ItemDetails struct {
+	// DueAt is when the todo should be done, or nil for no deadline.
+	DueAt *time.Time
+
+	// Priority is low, normal, high or urgent. Empty means normal.
+	Priority string
+
+	Notes string
+
+	// Tags label the todo. They are trimmed, lower-cased and deduplicated.
+	Tags []string
+}
+
+// ItemPatch holds the fields of a partial todo update. Nil fields are left unchanged.
+type ItemPatch struct {
+	Task   *string
+	Status *string
+
+	// DueAt sets the due date. ClearDueAt removes it and cannot be combined with DueAt.
+	DueAt      *time.Time
+	ClearDueAt bool
+
+	Priority *string
+	Notes    *string
+	Tags     *[]string
+}
+
+// Add creates a new todo item owned by the caller, or in the service's list.
+func (s *TodoService) Add(ctx context.Context, todo string, details ItemDetails) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Add")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := s.scope(ctx, RoleEditor)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	return s.add(ctx, scope, 0, todo, details)
+}
+
+// add validates and stores a new todo item in the scope, as a subtask of parentID if it is not zero.
+func (s *TodoService) add(ctx context.Context, scope db.Scope, parentID int64, todo string, details ItemDetails) (db.Item, error) {
+	item, err := newItem(ctx, scope, parentID, todo, details)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	// Duplicates within the scope are rejected by the store's unique constraint.
+	if item, err = s.db.InsertItem(audited(ctx, db.ActionCreate), item); err != nil {
+		return db.Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to add todo")
+	}
+
+	s.log(ctx).InfoContext(ctx, "todo created", "todo_id", item.ID)
+
+	return item, nil
+}
+
+// newItem validates a new todo item of the caller in the scope and returns it ready to store.
+func newItem(ctx context.Context, scope db.Scope, parentID int64, todo string, details ItemDetails) (_ db.Item, err error) {
 	if todo == "" {
-		return apierror.Wrap(
+		return db.Item{}, apierror.Wrap(
 			apierror.ErrInvalidRequest,
 			http.StatusBadRequest,
 			"todo item cannot be empty",
+		).WithDetails(apierror.FieldError{Field: "item", Message: "must not be empty"})
+	}
+
+	if details, err = checkDetails(details); err != nil {
+		return db.Item{}, err
+	}
+
+	return db.Item{
+		OwnerID: ownerID(ctx),
+
+		ListID: scope.ListID,
+
+		ParentID: parentID,
+
+		Task: todo,
+
+		Status: string(StatusToBeStarted),
+
+		DueAt: details.DueAt,
+
+		Priority: details.Priority,
+
+		Notes: details.Notes,
+
+		Tags: details.Tags,
+	}, nil
+}
+
+// Get returns a single todo item of the caller by its ID.
+func (s *TodoService) Get(ctx context.Context, id int64) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Get", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := s.scope(ctx, RoleViewer)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	return s.db.GetItem(ctx, scope, id)
+}
+
+// editable returns an item the caller may change.
+func (s *TodoService) editable(ctx context.Context, id int64) (db.Item, error) {
+	scope, err := s.scope(ctx, RoleEditor)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	return s.db.GetItem(ctx, scope, id)
+}
+
+// Update replaces the task, status and details of an existing todo item.
+// A status change must be an allowed lifecycle transition.
+func (s *TodoService) Update(
+	ctx context.Context,
+	id int64,
+	task, status string,
+	details ItemDetails,
+) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Update", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	current, err := s.editable(ctx, id)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	return s.replace(ctx, current, task, status, details)
+}
+
+// Patch applies a partial update to an existing todo item.
+func (s *TodoService) Patch(ctx context.Context, id int64, patch ItemPatch) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Patch", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	current, err := s.editable(ctx, id)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	if patch.DueAt != nil && patch.ClearDueAt {
+		return db.Item{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			"due date cannot be both set and cleared",
 		)
 	}
 
-	items, err := s.ListTodos(ctx)
+	task, status := current.Task, current.Status
+	details := ItemDetails{DueAt: current.DueAt, Priority: current.Priority, Notes: current.Notes, Tags: current.Tags}
+	if patch.Task != nil {
+		task = *patch.Task
+	}
+
+	if patch.Status != nil {
+		status = *patch.Status
+	}
+
+	if patch.DueAt != nil || patch.ClearDueAt {
+		details.DueAt = patch.DueAt
+	}
+
+	if patch.Priority != nil {
+		details.Priority = *patch.Priority
+	}
+
+	if patch.Notes != nil {
+		details.Notes = *patch.Notes
+	}
+
+	if patch.Tags != nil {
+		details.Tags = *patch.Tags
+	}
+
+	return s.replace(ctx, current, task, status, details)
+}
+
+// Transition moves a todo item to a new status if the lifecycle allows it.
+func (s *TodoService) Transition(ctx context.Context, id int64, status string) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Transition", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	to, err := ParseStatus(status)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	item, err := s.editable(ctx, id)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	if item.Version, err = s.precondition(item); err != nil {
+		return db.Item{}, err
+	}
+
+	if err = checkTransition(Status(item.Status), to); err != nil {
+		return db.Item{}, err
+	}
+
+	if err = s.checkSubtasks(item, to); err != nil {
+		return db.Item{}, err
+	}
+
+	item.Status = string(to)
+
+	return s.update(ctx, db.ActionTransition, item)
+}
+
+// replace validates and stores a new task, status and details for the current item.
+func (s *TodoService) replace(ctx context.Context, current db.Item, task, status string, details ItemDetails) (db.Item, error) {
+	version, err := s.precondition(current)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	if task == "" {
+		return db.Item{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			"todo item cannot be empty",
+		).WithDetails(apierror.FieldError{Field: "item", Message: "must not be empty"})
+	}
+
+	if status == "" {
+		return db.Item{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			"todo status cannot be empty",
+		).WithDetails(apierror.FieldError{Field: "status", Message: "must not be empty"})
+	}
+
+	to, err := ParseStatus(status)
 	if err != nil {
-		return apierror.Wrap(err, http.StatusInternalServerError, "failed to check for duplicates")
+		return db.Item{}, err
 	}
 
-	for _, t := range items {
-		if t.Task == todo {
-			return apierror.ErrDuplicateTodo
+	if details, err = checkDetails(details); err != nil {
+		return db.Item{}, err
+	}
+
+	if from := Status(current.Status); from != to {
+		if err = checkTransition(from, to); err != nil {
+			return db.Item{}, err
+		}
+
+		if err = s.checkSubtasks(current, to); err != nil {
+			return db.Item{}, err
 		}
 	}
 
-	if insertErr := s.db.InsertItem(ctx, db.Item{
-		Task: todo,
+	return s.update(ctx, db.ActionUpdate, db.Item{
+		ID: current.ID,
+
+		OwnerID: current.OwnerID,
+
+		ListID: current.ListID,
+
+		ParentID: current.ParentID,
+
+		Version: version,
+
+		Task: task,
+
+		Status: status,
+
+		DueAt: details.DueAt,
+
+		Priority: details.Priority,
+
+		Notes: details.Notes,
+
+		Tags: details.Tags,
+	})
+}
+
+// checkDetails validates details and returns them with the priority defaulted and the tags normalized.
+func checkDetails(details ItemDetails) (ItemDetails, error) {
+	priority, err := ParsePriority(details.Priority)
+	if err != nil {
+		return ItemDetails{}, err
+	}
+	details.Priority = string(priority)
+
+	if details.Tags, err = normalizeTags(details.Tags, "tags"); err != nil {
+		return ItemDetails{}, err
+	}
+
+	if len(details.Notes) > MaxNotesLength {
+		return ItemDetails{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("notes cannot be longer than %d bytes", MaxNotesLength),
+		).WithDetails(apierror.FieldError{Field: "notes", Message: fmt.Sprintf("must be at most %d bytes", MaxNotesLength)})
+	}
+
+	return details, nil
+}
+
+// update stores the task and status of an existing todo item, recording the change as action.
+func (s *TodoService) update(ctx context.Context, action string, item db.Item) (db.Item, error) {
+	updated, err := s.db.UpdateItem(audited(ctx, action), item)
+	if err != nil {
+		if s.ifMatch == nil && errors.Is(err, apierror.ErrPreconditionFailed) {
+			// The caller sent no If-Match, so the todo only changed after it was read here.
+			return db.Item{}, apierror.Wrap(apierror.ErrConcurrentUpdate, http.StatusConflict, "todo was changed concurrently")
+		}
+
+		return db.Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to update todo")
+	}
+
+	s.log(ctx).InfoContext(ctx, "todo updated", "todo_id", updated.ID, "status", updated.Status)
+
+	return updated, nil
+}
+
+// Delete moves a todo item of the caller to the trash by its ID, together with its subtasks.
+func (s *TodoService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Delete", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
 
-		Status: "TO_BE_STARTED",
-	}); insertErr != nil {
-		return apierror.Wrap(insertErr, http.StatusInternalServerError, "failed to insert todo item")
+	scope, err := s.scope(ctx, RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	var version int64
+	if s.ifMatch != nil {
+		current, getErr := s.db.GetItem(ctx, scope, id)
+		if getErr != nil {
+			return getErr
+		}
+
+		if version, err = s.precondition(current); err != nil {
+			return err
+		}
+	}
+
+	if err := s.db.DeleteItem(audited(ctx, db.ActionDelete), scope, id, version); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to delete todo")
 	}
 
+	s.log(ctx).InfoContext(ctx, "todo moved to trash", "todo_id", id)
+
 	return nil
 }
 
-// Search finds todos containing the query string.
-func (s *TodoService) Search(ctx context.Context, query string) ([]string, error) {
-	items, err := s.ListTodos(ctx)
+// Search finds todos of the caller matching the query, most relevant first.
+// A limit of zero uses DefaultPageSize.
+func (s *TodoService) Search(ctx context.Context, query string, limit, offset int) (_ SearchPage, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Search")
+	defer func() { tracing.End(span, err) }()
+
+	if strings.TrimSpace(query) == "" {
+		return SearchPage{}, apierror.ErrEmptySearchQuery
+	}
+
+	limit, err = pageLimit(limit)
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	if offset < 0 {
+		return SearchPage{}, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "offset cannot be negative").
+			WithDetails(apierror.FieldError{Field: "offset", Message: "must not be negative"})
+	}
+
+	scope, err := s.scope(ctx, RoleViewer)
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	// Fetch one extra result to find out whether there is another page.
+	results, err := s.db.SearchItems(ctx, db.SearchOptions{
+		Scope: scope,
+
+		Query: query,
+
+		Limit: limit + 1,
+
+		Offset: offset,
+	})
 	if err != nil {
-		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to list todos")
+		return SearchPage{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to search todos")
+	}
+
+	page := SearchPage{Results: results}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+
+	return page, nil
+}
+
+// pageLimit applies the default page size and rejects sizes outside 1 to MaxPageSize.
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultPageSize, nil
 	}
 
-	var results []string
-	for _, todo := range items {
-		if strings.Contains(strings.ToLower(todo.Task), strings.ToLower(query)) {
-			results = append(results, todo.Task)
+	if limit < 0 || limit > MaxPageSize {
+		return 0, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("limit must be between 1 and %d", MaxPageSize),
+		).WithDetails(apierror.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxPageSize)})
+	}
+
+	return limit, nil
+}
+
+// ListTodos lists a filtered, sorted page of the caller's todo items.
+func (s *TodoService) ListTodos(ctx context.Context, opts ListOptions) (_ ListPage, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.ListTodos")
+	defer func() { tracing.End(span, err) }()
+
+	return s.listScoped(ctx, opts, false)
+}
+
+// listScoped lists a page of the caller's todo items, or of the service's list, in the trash
+// or outside it.
+func (s *TodoService) listScoped(ctx context.Context, opts ListOptions, trashed bool) (ListPage, error) {
+	query, err := opts.toDB(s.now())
+	if err != nil {
+		return ListPage{}, err
+	}
+	query.Trashed = trashed
+
+	if query.Scope, err = s.scope(ctx, RoleViewer); err != nil {
+		return ListPage{}, err
+	}
+
+	return s.list(ctx, query)
+}
+
+// ListAllTodos lists a filtered, sorted page of the todo items of every owner, or only of
+// owner if it is not empty. It is meant for administrators; callers must check access.
+func (s *TodoService) ListAllTodos(ctx context.Context, owner string, opts ListOptions) (_ ListPage, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.ListAllTodos")
+	defer func() { tracing.End(span, err) }()
+
+	query, err := opts.toDB(s.now())
+	if err != nil {
+		return ListPage{}, err
+	}
+	query.Scope = db.Scope{OwnerID: owner}
+	query.AllOwners = owner == ""
+
+	return s.list(ctx, query)
+}
+
+// list fetches a page of items, setting NextCursor if there are more.
+func (s *TodoService) list(ctx context.Context, query db.ListOptions) (ListPage, error) {
+	limit := query.Limit
+
+	// Fetch one extra item to find out whether there is another page.
+	query.Limit++
+	items, err := s.db.ListItems(ctx, query)
+	if err != nil {
+		return ListPage{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to get todos from database")
+	}
+
+	page := ListPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = encodeCursor(query.Offset + limit)
+	}
+
+	return page, nil
+}
+
+// toDB validates the options and converts them to a db.ListOptions. Overdue is relative to now.
+func (o ListOptions) toDB(now time.Time) (db.ListOptions, error) {
+	limit, err := pageLimit(o.Limit)
+	if err != nil {
+		return db.ListOptions{}, err
+	}
+
+	offset := o.Offset
+	if o.Cursor != "" {
+		if offset != 0 {
+			return db.ListOptions{}, apierror.Wrap(
+				apierror.ErrInvalidRequest,
+				http.StatusBadRequest,
+				"cursor and offset cannot be combined",
+			)
+		}
+
+		if offset, err = decodeCursor(o.Cursor); err != nil {
+			return db.ListOptions{}, err
 		}
 	}
 
-	return results, nil
+	if offset < 0 {
+		return db.ListOptions{}, apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "offset cannot be negative").
+			WithDetails(apierror.FieldError{Field: "offset", Message: "must not be negative"})
+	}
+
+	for _, status := range o.Statuses {
+		if _, err = ParseStatus(status); err != nil {
+			return db.ListOptions{}, err
+		}
+	}
+
+	for _, priority := range o.Priorities {
+		// ParsePriority reads the empty priority as normal, which is not what a filter means.
+		if priority == "" {
+			return db.ListOptions{}, apierror.Wrap(apierror.ErrInvalidPriority, http.StatusBadRequest, "priority cannot be empty").
+				WithDetails(apierror.FieldError{Field: "priority", Message: "must be low, normal, high or urgent"})
+		}
+
+		if _, err = ParsePriority(priority); err != nil {
+			return db.ListOptions{}, err
+		}
+	}
+
+	// Filters come from the repeated tag query parameter, so errors name that.
+	tags, err := normalizeTags(o.Tags, "tag")
+	if err != nil {
+		return db.ListOptions{}, err
+	}
+
+	dueBefore, excluded := o.DueBefore, []string(nil)
+	if o.Overdue {
+		if dueBefore.IsZero() || now.Before(dueBefore) {
+			dueBefore = now
+		}
+		excluded = []string{string(StatusDone), string(StatusCancelled)}
+	}
+
+	column, desc := strings.CutPrefix(o.Sort, "-")
+	if column != "" && !slices.Contains(sortFields, column) {
+		return db.ListOptions{}, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("sort must be one of %s, optionally prefixed with -", strings.Join(sortFields, ", ")),
+		).WithDetails(apierror.FieldError{Field: "sort", Message: "unknown sort field " + strconv.Quote(column)})
+	}
+
+	return db.ListOptions{
+		Statuses: o.Statuses,
+
+		ExcludeStatuses: excluded,
+
+		Priorities: o.Priorities,
+
+		DueBefore: dueBefore,
+
+		DueAfter: o.DueAfter,
+
+		Tags: tags,
+
+		AllTags: o.AllTags,
+
+		Sort: column,
+
+		Desc: desc,
+
+		Limit: limit,
+
+		Offset: offset,
+	}, nil
 }
 
-// ListTodos lists all todo items.
-func (s *TodoService) ListTodos(ctx context.Context) ([]db.Item, error) {
-	items, err := s.db.GetAllItems(ctx)
+// sortFields are the values ListOptions.Sort accepts, besides the empty default.
+var sortFields = []string{db.SortCreatedAt, db.SortTask, db.SortStatus, db.SortDueAt, db.SortPriority}
+
+// encodeCursor returns an opaque cursor pointing at offset.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset a cursor from encodeCursor points at.
+func decodeCursor(cursor string) (int, error) {
+	invalid := apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid cursor").
+		WithDetails(apierror.FieldError{Field: "cursor", Message: "malformed cursor"})
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to get todos from database")
+		return 0, invalid
+	}
+
+	value, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, invalid
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+
+	return offset, nil
+}
+
+// ownerID returns the owner of the caller's todos: the subject of the principal in ctx,
+// or the empty owner when callers are not identified.
+func ownerID(ctx context.Context) string {
+	principal, _ := auth.FromContext(ctx)
+
+	return principal.Subject
+}
+
+// scope returns the todos the caller may access with at least the role need: their personal
+// todos, or the todos of the service's list if the caller is a member with a sufficient role.
+// The list is not found for non-members, so they cannot learn that it exists.
+func (s *TodoService) scope(ctx context.Context, need Role) (db.Scope, error) {
+	if s.listID == 0 {
+		return db.Scope{OwnerID: ownerID(ctx)}, nil
 	}
 
-	return items, nil
+	if _, err := s.member(ctx, s.listID, need); err != nil {
+		return db.Scope{}, err
+	}
+
+	return db.Scope{ListID: s.listID}, nil
+}
+
+// log returns the request logger from ctx, or the service logger.
+func (s *TodoService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
 }
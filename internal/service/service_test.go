@@ -2,37 +2,91 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
 	"github.com/brkcnr/golandworks-api/internal/db"
 	"github.com/brkcnr/golandworks-api/internal/service"
 )
 
-// mockDB implements db.Storer interface for testing
-type mockDB struct {
-	items []db.Item
-	err   error
+// failingDB is a db.Storer whose every call fails with err.
+type failingDB struct {
+	db.Storer
+	err error
 }
 
-func (m *mockDB) InsertItem(_ context.Context, item db.Item) error {
-	if m.err != nil {
-		return m.err
-	}
-	m.items = append(m.items, item)
-	return nil
+func (f failingDB) InsertItem(context.Context, db.Item) (db.Item, error) {
+	return db.Item{}, f.err
+}
+
+func (f failingDB) GetAllItems(context.Context) ([]db.Item, error) {
+	return nil, f.err
+}
+
+func (f failingDB) GetItem(context.Context, db.Scope, int64) (db.Item, error) {
+	return db.Item{}, f.err
+}
+
+func (f failingDB) UpdateItem(context.Context, db.Item) (db.Item, error) {
+	return db.Item{}, f.err
+}
+
+func (f failingDB) DeleteItem(context.Context, db.Scope, int64, int64) error {
+	return f.err
+}
+
+func (f failingDB) SearchItems(context.Context, db.SearchOptions) ([]db.SearchResult, error) {
+	return nil, f.err
+}
+
+func (f failingDB) ListItems(context.Context, db.ListOptions) ([]db.Item, error) {
+	return nil, f.err
+}
+
+func (f failingDB) CountItems(context.Context) (map[string]int64, error) {
+	return nil, f.err
+}
+
+func (f failingDB) InsertList(context.Context, db.List, string) (db.List, error) {
+	return db.List{}, f.err
+}
+
+func (f failingDB) ListLists(context.Context, string) ([]db.MemberList, error) {
+	return nil, f.err
+}
+
+func (f failingDB) GetMember(context.Context, int64, string) (db.Member, error) {
+	return db.Member{}, f.err
 }
 
-func (m *mockDB) GetAllItems(_ context.Context) ([]db.Item, error) {
-	if m.err != nil {
-		return nil, m.err
+// newStore returns an in-memory store seeded with items, or a failingDB if err is set.
+// Seeded items are assigned IDs starting at 1 in slice order.
+func newStore(t *testing.T, items []db.Item, err error) db.Storer {
+	t.Helper()
+
+	if err != nil {
+		return failingDB{err: err}
+	}
+
+	store := db.NewMemory()
+	for _, item := range items {
+		if _, insertErr := store.InsertItem(context.Background(), item); insertErr != nil {
+			t.Fatalf("failed to seed store: %v", insertErr)
+		}
 	}
-	return m.items, nil
+
+	return store
 }
 
 func TestNew(t *testing.T) {
-	mock := &mockDB{}
-	svc := service.New(service.WithDB(mock))
+	svc := service.New(service.WithDB(db.NewMemory()))
 	if svc == nil {
 		t.Error("New() returned nil service")
 	}
@@ -68,10 +122,10 @@ func TestTodoService_Add(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockDB{items: tt.dbItems, err: tt.dbErr}
-			svc := service.New(service.WithDB(mock))
+			store := newStore(t, tt.dbItems, tt.dbErr)
+			svc := service.New(service.WithDB(store))
 
-			err := svc.Add(context.Background(), tt.todo)
+			_, err := svc.Add(context.Background(), tt.todo, service.ItemDetails{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -79,6 +133,39 @@ func TestTodoService_Add(t *testing.T) {
 	}
 }
 
+func TestTodoService_Add_Concurrent(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+
+	const workers = 20
+
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Add(context.Background(), "same todo", service.ItemDetails{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var added int
+	for err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case !errors.Is(err, apierror.ErrDuplicateTodo):
+			t.Errorf("Add() error = %v, want %v", err, apierror.ErrDuplicateTodo)
+		}
+	}
+
+	if added != 1 {
+		t.Errorf("Add() succeeded %d times, want 1", added)
+	}
+}
+
 func TestTodoService_Search(t *testing.T) {
 	mockItems := []db.Item{
 		{Task: "Buy groceries"},
@@ -87,12 +174,15 @@ func TestTodoService_Search(t *testing.T) {
 	}
 
 	tests := []struct {
-		name      string
-		query     string
-		dbItems   []db.Item
-		dbErr     error
-		want      []string
-		wantErr   bool
+		name     string
+		query    string
+		limit    int
+		offset   int
+		dbItems  []db.Item
+		dbErr    error
+		want     []string
+		wantNext *int
+		wantErr  bool
 	}{
 		{
 			name:    "find matching items",
@@ -101,6 +191,31 @@ func TestTodoService_Search(t *testing.T) {
 			want:    []string{"Buy groceries", "Buy new shoes"},
 			wantErr: false,
 		},
+		{
+			name:    "prefix of every word",
+			query:   "buy sho",
+			dbItems: mockItems,
+			want:    []string{"Buy new shoes"},
+			wantErr: false,
+		},
+		{
+			name:     "first page",
+			query:    "buy",
+			limit:    1,
+			dbItems:  mockItems,
+			want:     []string{"Buy groceries"},
+			wantNext: intPtr(1),
+			wantErr:  false,
+		},
+		{
+			name:    "last page",
+			query:   "buy",
+			limit:   1,
+			offset:  1,
+			dbItems: mockItems,
+			want:    []string{"Buy new shoes"},
+			wantErr: false,
+		},
 		{
 			name:    "no matches",
 			query:   "nonexistent",
@@ -112,61 +227,135 @@ func TestTodoService_Search(t *testing.T) {
 			name:    "empty query",
 			query:   "",
 			dbItems: mockItems,
-			want:    []string{"Buy groceries", "Do laundry", "Buy new shoes"},
-			wantErr: false,
+			wantErr: true,
+		},
+		{
+			name:    "limit too large",
+			query:   "buy",
+			limit:   service.MaxPageSize + 1,
+			dbItems: mockItems,
+			wantErr: true,
+		},
+		{
+			name:    "database error",
+			query:   "buy",
+			dbErr:   fmt.Errorf("database error"),
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockDB{items: tt.dbItems, err: tt.dbErr}
-			svc := service.New(service.WithDB(mock))
+			store := newStore(t, tt.dbItems, tt.dbErr)
+			svc := service.New(service.WithDB(store))
 
-			got, err := svc.Search(context.Background(), tt.query)
+			got, err := svc.Search(context.Background(), tt.query, tt.limit, tt.offset)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Search() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if len(got) != len(tt.want) {
-				t.Errorf("Search() got = %v, want %v", got, tt.want)
+			if len(got.Results) != len(tt.want) {
+				t.Errorf("Search() got = %v, want %v", got.Results, tt.want)
 				return
 			}
 
-			for i, v := range got {
-				if v != tt.want[i] {
-					t.Errorf("Search() got[%d] = %v, want[%d] = %v", i, v, i, tt.want[i])
+			for i, v := range got.Results {
+				if v.Task != tt.want[i] {
+					t.Errorf("Search() got[%d] = %v, want[%d] = %v", i, v.Task, i, tt.want[i])
+				}
+				if v.ID == 0 || v.Score <= 0 {
+					t.Errorf("Search() got[%d] = %v, want an ID and a positive score", i, v)
 				}
 			}
+
+			if (got.NextOffset == nil) != (tt.wantNext == nil) ||
+				(got.NextOffset != nil && *got.NextOffset != *tt.wantNext) {
+				t.Errorf("Search() next offset = %v, want %v", got.NextOffset, tt.wantNext)
+			}
 		})
 	}
 }
 
+func intPtr(n int) *int {
+	return &n
+}
+
 func TestTodoService_ListTodos(t *testing.T) {
 	mockItems := []db.Item{
-		{Task: "Task 1"},
-		{Task: "Task 2"},
+		{Task: "Task 1", Status: "DONE"},
+		{Task: "Task 2", Status: "TO_BE_STARTED"},
+		{Task: "Task 3", Status: "IN_PROGRESS"},
 	}
 
 	tests := []struct {
-		name    string
-		dbItems []db.Item
-		dbErr   error
-		want    []db.Item
-		wantErr bool
+		name       string
+		opts       service.ListOptions
+		dbItems    []db.Item
+		dbErr      error
+		want       []string
+		wantCursor bool
+		wantErr    bool
 	}{
 		{
 			name:    "successful list",
 			dbItems: mockItems,
-			want:    mockItems,
+			want:    []string{"Task 1", "Task 2", "Task 3"},
 			wantErr: false,
 		},
 		{
 			name:    "empty list",
 			dbItems: []db.Item{},
-			want:    []db.Item{},
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name:    "filter by status",
+			opts:    service.ListOptions{Statuses: []string{"DONE", "IN_PROGRESS"}},
+			dbItems: mockItems,
+			want:    []string{"Task 1", "Task 3"},
+			wantErr: false,
+		},
+		{
+			name:    "sort by status descending",
+			opts:    service.ListOptions{Sort: "-status"},
+			dbItems: mockItems,
+			want:    []string{"Task 2", "Task 3", "Task 1"},
+			wantErr: false,
+		},
+		{
+			name:       "first page",
+			opts:       service.ListOptions{Limit: 2},
+			dbItems:    mockItems,
+			want:       []string{"Task 1", "Task 2"},
+			wantCursor: true,
+			wantErr:    false,
+		},
+		{
+			name:    "page by offset",
+			opts:    service.ListOptions{Limit: 2, Offset: 2},
+			dbItems: mockItems,
+			want:    []string{"Task 3"},
 			wantErr: false,
 		},
+		{
+			name:    "invalid status filter",
+			opts:    service.ListOptions{Statuses: []string{"SOMEDAY"}},
+			dbItems: mockItems,
+			wantErr: true,
+		},
+		{
+			name:    "invalid sort",
+			opts:    service.ListOptions{Sort: "id; DROP TABLE todo_items"},
+			dbItems: mockItems,
+			wantErr: true,
+		},
+		{
+			name:    "invalid cursor",
+			opts:    service.ListOptions{Cursor: "not a cursor"},
+			dbItems: mockItems,
+			wantErr: true,
+		},
 		{
 			name:    "database error",
 			dbErr:   fmt.Errorf("database error"),
@@ -176,20 +365,578 @@ func TestTodoService_ListTodos(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockDB{items: tt.dbItems, err: tt.dbErr}
-			svc := service.New(service.WithDB(mock))
+			store := newStore(t, tt.dbItems, tt.dbErr)
+			svc := service.New(service.WithDB(store))
 
-			got, err := svc.ListTodos(context.Background())
+			got, err := svc.ListTodos(context.Background(), tt.opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListTodos() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if !tt.wantErr {
-				if len(got) != len(tt.want) {
-					t.Errorf("ListTodos() got = %v, want %v", got, tt.want)
-				}
+			if tt.wantErr {
+				return
+			}
+
+			tasks := make([]string, 0, len(got.Items))
+			for _, item := range got.Items {
+				tasks = append(tasks, item.Task)
+			}
+
+			if !slices.Equal(tasks, tt.want) {
+				t.Errorf("ListTodos() got = %v, want %v", tasks, tt.want)
+			}
+
+			if (got.NextCursor != "") != tt.wantCursor {
+				t.Errorf("ListTodos() next cursor = %q, want cursor %v", got.NextCursor, tt.wantCursor)
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestTodoService_ListTodos_Cursor(t *testing.T) {
+	var items []db.Item
+	for i := range 5 {
+		items = append(items, db.Item{Task: fmt.Sprintf("Task %d", i), Status: "TO_BE_STARTED"})
+	}
+
+	store := newStore(t, items, nil)
+	svc := service.New(service.WithDB(store))
+
+	var (
+		seen   []string
+		cursor string
+	)
+
+	for range len(items) {
+		page, err := svc.ListTodos(context.Background(), service.ListOptions{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListTodos() error = %v", err)
+		}
+
+		for _, item := range page.Items {
+			seen = append(seen, item.Task)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != len(items) {
+		t.Errorf("paging with cursors returned %v, want all %d items", seen, len(items))
+	}
+}
+
+func TestTodoService_Get(t *testing.T) {
+	store := newStore(t, []db.Item{{ID: 1, Task: "Task 1", Status: "TO_BE_STARTED"}}, nil)
+	svc := service.New(service.WithDB(store))
+
+	got, err := svc.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Task != "Task 1" {
+		t.Errorf("Get() task = %v, want %v", got.Task, "Task 1")
+	}
+
+	if _, err = svc.Get(context.Background(), 2); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestTodoService_Update(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int64
+		task    string
+		status  string
+		wantErr bool
+	}{
+		{
+			name:   "valid update",
+			id:     1,
+			task:   "Task 1 updated",
+			status: "IN_PROGRESS",
+		},
+		{
+			name:    "illegal transition",
+			id:      1,
+			task:    "Task 1",
+			status:  "DONE",
+			wantErr: true,
+		},
+		{
+			name:    "unknown status",
+			id:      1,
+			task:    "Task 1",
+			status:  "SOMEDAY",
+			wantErr: true,
+		},
+		{
+			name:    "empty task",
+			id:      1,
+			task:    "",
+			status:  "DONE",
+			wantErr: true,
+		},
+		{
+			name:    "empty status",
+			id:      1,
+			task:    "Task 1",
+			status:  "",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate of another item",
+			id:      1,
+			task:    "Task 2",
+			status:  "TO_BE_STARTED",
+			wantErr: true,
+		},
+		{
+			name:    "missing item",
+			id:      3,
+			task:    "Task 3",
+			status:  "TO_BE_STARTED",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t, []db.Item{
+				{ID: 1, Task: "Task 1", Status: "TO_BE_STARTED"},
+				{ID: 2, Task: "Task 2", Status: "TO_BE_STARTED"},
+			}, nil)
+			svc := service.New(service.WithDB(store))
+
+			got, err := svc.Update(context.Background(), tt.id, tt.task, tt.status, service.ItemDetails{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && (got.Task != tt.task || got.Status != tt.status) {
+				t.Errorf("Update() got = %v, want task %v status %v", got, tt.task, tt.status)
+			}
+		})
+	}
+}
+
+func TestTodoService_Patch(t *testing.T) {
+	store := newStore(t, []db.Item{{ID: 1, Task: "Task 1", Status: "TO_BE_STARTED"}}, nil)
+	svc := service.New(service.WithDB(store))
+
+	status := "IN_PROGRESS"
+	got, err := svc.Patch(context.Background(), 1, service.ItemPatch{Status: &status})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if got.Task != "Task 1" || got.Status != status {
+		t.Errorf("Patch() got = %v, want task %v status %v", got, "Task 1", status)
+	}
+}
+
+func TestTodoService_Transition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{name: "start", from: "TO_BE_STARTED", to: "IN_PROGRESS"},
+		{name: "finish", from: "IN_PROGRESS", to: "DONE"},
+		{name: "cancel", from: "IN_PROGRESS", to: "CANCELLED"},
+		{name: "reopen done", from: "DONE", to: "IN_PROGRESS"},
+		{name: "restart cancelled", from: "CANCELLED", to: "TO_BE_STARTED"},
+		{name: "skip in progress", from: "TO_BE_STARTED", to: "DONE", wantErr: apierror.ErrInvalidTransition},
+		{name: "same status", from: "DONE", to: "DONE", wantErr: apierror.ErrInvalidTransition},
+		{name: "cancel done", from: "DONE", to: "CANCELLED", wantErr: apierror.ErrInvalidTransition},
+		{name: "unknown status", from: "TO_BE_STARTED", to: "SOMEDAY", wantErr: apierror.ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t, []db.Item{{ID: 1, Task: "Task 1", Status: tt.from}}, nil)
+			svc := service.New(service.WithDB(store))
+
+			got, err := svc.Transition(context.Background(), 1, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transition() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && got.Status != tt.to {
+				t.Errorf("Transition() status = %v, want %v", got.Status, tt.to)
+			}
+		})
+	}
+}
+
+func TestTodoService_Delete(t *testing.T) {
+	store := newStore(t, []db.Item{{ID: 1, Task: "Task 1", Status: "TO_BE_STARTED"}}, nil)
+	svc := service.New(service.WithDB(store))
+
+	if err := svc.Delete(context.Background(), 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	items, err := store.GetAllItems(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllItems() error = %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Delete() left %d items, want 0", len(items))
+	}
+
+	if err = svc.Delete(context.Background(), 1); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestTodoService_Owners(t *testing.T) {
+	store := db.NewMemory()
+	svc := service.New(service.WithDB(store))
+
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+
+	aliceItem, err := svc.Add(alice, "Water the plants", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if aliceItem.OwnerID != "alice" {
+		t.Errorf("Add() owner = %q, want %q", aliceItem.OwnerID, "alice")
+	}
+
+	if _, err = svc.Add(alice, "Water the plants", service.ItemDetails{}); !errors.Is(err, apierror.ErrDuplicateTodo) {
+		t.Errorf("Add() of own duplicate error = %v, want %v", err, apierror.ErrDuplicateTodo)
+	}
+
+	// Duplicate detection is per owner.
+	if _, err = svc.Add(bob, "Water the plants", service.ItemDetails{}); err != nil {
+		t.Fatalf("Add() of another owner's task error = %v", err)
+	}
+
+	if _, err = svc.Get(bob, aliceItem.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() of another owner's todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err = svc.Transition(bob, aliceItem.ID, "IN_PROGRESS"); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Transition() of another owner's todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if err = svc.Delete(bob, aliceItem.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Delete() of another owner's todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	if _, err = svc.Update(alice, aliceItem.ID, "Water the garden", "IN_PROGRESS", service.ItemDetails{}); err != nil {
+		t.Fatalf("Update() of own todo error = %v", err)
+	}
+
+	page, err := svc.ListTodos(bob, service.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListTodos() error = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].OwnerID != "bob" {
+		t.Errorf("ListTodos() = %v, want only bob's todo", page.Items)
+	}
+
+	search, err := svc.Search(bob, "garden", 0, 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(search.Results) != 0 {
+		t.Errorf("Search() = %v, want none of alice's todos", search.Results)
+	}
+
+	tests := []struct {
+		name   string
+		owner  string
+		owners []string
+	}{
+		{name: "every owner", owner: "", owners: []string{"alice", "bob"}},
+		{name: "one owner", owner: "alice", owners: []string{"alice"}},
+		{name: "unknown owner", owner: "carol", owners: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := svc.ListAllTodos(context.Background(), tt.owner, service.ListOptions{Sort: "created_at"})
+			if err != nil {
+				t.Fatalf("ListAllTodos() error = %v", err)
+			}
+
+			var owners []string
+			for _, item := range all.Items {
+				owners = append(owners, item.OwnerID)
+			}
+			if !slices.Equal(owners, tt.owners) {
+				t.Errorf("ListAllTodos() owners = %v, want %v", owners, tt.owners)
+			}
+		})
+	}
+}
+
+func TestTodoService_Details(t *testing.T) {
+	now := time.Date(2030, 1, 10, 12, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+
+	svc := service.New(
+		service.WithDB(db.NewMemory()),
+		service.WithClock(func() time.Time { return now }),
+	)
+	ctx := context.Background()
+
+	late, err := svc.Add(ctx, "File taxes", service.ItemDetails{DueAt: &yesterday, Priority: "urgent", Notes: "ask the accountant"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if late.Priority != "urgent" || late.DueAt == nil || !late.DueAt.Equal(yesterday) || late.Notes != "ask the accountant" {
+		t.Errorf("Add() = %+v, want the details", late)
+	}
+
+	plain, err := svc.Add(ctx, "Water the plants", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if plain.Priority != string(service.PriorityNormal) {
+		t.Errorf("Add() priority = %q, want %q", plain.Priority, service.PriorityNormal)
+	}
+
+	done, err := svc.Add(ctx, "Renew passport", service.ItemDetails{DueAt: &yesterday})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	for _, status := range []string{"IN_PROGRESS", "DONE"} {
+		if _, err = svc.Transition(ctx, done.ID, status); err != nil {
+			t.Fatalf("Transition() error = %v", err)
+		}
+	}
+
+	if _, err = svc.Add(ctx, "Book flights", service.ItemDetails{DueAt: &tomorrow, Priority: "high"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	errTests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{
+			name:    "unknown priority",
+			call:    func() error { _, err := svc.Add(ctx, "Call mum", service.ItemDetails{Priority: "asap"}); return err },
+			wantErr: apierror.ErrInvalidPriority,
+		},
+		{
+			name: "notes too long",
+			call: func() error {
+				_, err := svc.Add(ctx, "Call mum", service.ItemDetails{Notes: strings.Repeat("x", service.MaxNotesLength+1)})
+				return err
+			},
+			wantErr: apierror.ErrInvalidRequest,
+		},
+		{
+			name: "due date set and cleared",
+			call: func() error {
+				_, err := svc.Patch(ctx, plain.ID, service.ItemPatch{DueAt: &tomorrow, ClearDueAt: true})
+				return err
+			},
+			wantErr: apierror.ErrInvalidRequest,
+		},
+		{
+			name: "empty priority filter",
+			call: func() error {
+				_, err := svc.ListTodos(ctx, service.ListOptions{Priorities: []string{""}})
+				return err
+			},
+			wantErr: apierror.ErrInvalidPriority,
+		},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	listTests := []struct {
+		name string
+		opts service.ListOptions
+		want []string
+	}{
+		{name: "overdue", opts: service.ListOptions{Overdue: true}, want: []string{"File taxes"}},
+		{name: "high priorities", opts: service.ListOptions{Priorities: []string{"high", "urgent"}}, want: []string{"File taxes", "Book flights"}},
+		{name: "due after now", opts: service.ListOptions{DueAfter: now}, want: []string{"Book flights"}},
+		{name: "by due date", opts: service.ListOptions{Sort: "due_at"}, want: []string{"File taxes", "Renew passport", "Book flights", "Water the plants"}},
+		{name: "by priority", opts: service.ListOptions{Sort: "-priority"}, want: []string{"File taxes", "Book flights", "Renew passport", "Water the plants"}},
+	}
+
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := svc.ListTodos(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("ListTodos() error = %v", err)
+			}
+
+			var tasks []string
+			for _, item := range page.Items {
+				tasks = append(tasks, item.Task)
+			}
+			if !slices.Equal(tasks, tt.want) {
+				t.Errorf("ListTodos() = %v, want %v", tasks, tt.want)
+			}
+		})
+	}
+
+	priority := "low"
+	patched, err := svc.Patch(ctx, late.ID, service.ItemPatch{ClearDueAt: true, Priority: &priority})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if patched.DueAt != nil || patched.Priority != "low" || patched.Notes != late.Notes {
+		t.Errorf("Patch() = %+v, want no due date, low priority and the notes kept", patched)
+	}
+}
+
+func TestTodoService_IfMatch(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	ctx := context.Background()
+
+	item, err := svc.Add(ctx, "Paint the fence", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	notes := "white"
+	tests := []struct {
+		name        string
+		svc         *service.TodoService
+		call        func(svc *service.TodoService) error
+		wantErr     error
+		wantVersion int64
+	}{
+		{
+			name: "stale patch",
+			svc:  svc.IfMatch(item.Version + 1),
+			call: func(svc *service.TodoService) error {
+				_, err := svc.Patch(ctx, item.ID, service.ItemPatch{Notes: &notes})
+				return err
+			},
+			wantErr:     apierror.ErrPreconditionFailed,
+			wantVersion: 1,
+		},
+		{
+			name: "no versions",
+			svc:  svc.IfMatch(),
+			call: func(svc *service.TodoService) error {
+				_, err := svc.Transition(ctx, item.ID, "IN_PROGRESS")
+				return err
+			},
+			wantErr:     apierror.ErrPreconditionFailed,
+			wantVersion: 1,
+		},
+		{
+			name: "matching patch",
+			svc:  svc.IfMatch(0, item.Version),
+			call: func(svc *service.TodoService) error {
+				_, err := svc.Patch(ctx, item.ID, service.ItemPatch{Notes: &notes})
+				return err
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "stale update",
+			svc:  svc.IfMatch(1),
+			call: func(svc *service.TodoService) error {
+				_, err := svc.Update(ctx, item.ID, "Paint it", "TO_BE_STARTED", service.ItemDetails{})
+				return err
+			},
+			wantErr:     apierror.ErrPreconditionFailed,
+			wantVersion: 2,
+		},
+		{
+			name: "any version",
+			svc:  svc,
+			call: func(svc *service.TodoService) error {
+				_, err := svc.Transition(ctx, item.ID, "IN_PROGRESS")
+				return err
+			},
+			wantVersion: 3,
+		},
+		{
+			name:        "stale delete",
+			svc:         svc.IfMatch(2),
+			call:        func(svc *service.TodoService) error { return svc.Delete(ctx, item.ID) },
+			wantErr:     apierror.ErrPreconditionFailed,
+			wantVersion: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(tt.svc); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := svc.Get(ctx, item.ID)
+			if err != nil || got.Version != tt.wantVersion {
+				t.Errorf("Get() = %+v, %v, want version %d", got, err, tt.wantVersion)
+			}
+		})
+	}
+
+	if err = svc.IfMatch(3).Delete(ctx, item.ID); err != nil {
+		t.Errorf("Delete() at the current version error = %v", err)
+	}
+}
+
+// racingDB is a db.Storer that moves an item to status right after each read of it, so the
+// item read is stale by the time it is written.
+type racingDB struct {
+	db.Storer
+	status string
+}
+
+func (r racingDB) GetItem(ctx context.Context, scope db.Scope, id int64) (db.Item, error) {
+	item, err := r.Storer.GetItem(ctx, scope, id)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	changed := item
+	changed.Status = r.status
+	if _, err := r.Storer.UpdateItem(ctx, changed); err != nil {
+		return db.Item{}, err
+	}
+
+	return item, nil
+}
+
+func TestTodoService_ConcurrentChange(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemory()
+	item, err := service.New(service.WithDB(store)).Add(ctx, "Paint the fence", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	svc := service.New(service.WithDB(racingDB{Storer: store, status: "CANCELLED"}))
+	if _, err := svc.Transition(ctx, item.ID, "IN_PROGRESS"); !errors.Is(err, apierror.ErrConcurrentUpdate) {
+		t.Errorf("Transition() error = %v, want %v", err, apierror.ErrConcurrentUpdate)
+	}
+
+	// The todo is read at version 2 and changed to version 3 before it is written.
+	notes := "white"
+	if _, err := svc.IfMatch(item.Version+1).Patch(ctx, item.ID, service.ItemPatch{Notes: &notes}); !errors.Is(err, apierror.ErrPreconditionFailed) {
+		t.Errorf("Patch() with If-Match error = %v, want %v", err, apierror.ErrPreconditionFailed)
+	}
+
+	got, err := store.GetItem(ctx, db.ItemScope(item), item.ID)
+	if err != nil || got.Status != "CANCELLED" {
+		t.Errorf("GetItem() = %+v, %v, want the concurrent CANCELLED status kept", got, err)
+	}
+}
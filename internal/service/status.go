@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+)
+
+// Status is the lifecycle state of a todo item.
+type Status string
+
+// Todo item statuses.
+const (
+	StatusToBeStarted Status = "TO_BE_STARTED"
+	StatusInProgress  Status = "IN_PROGRESS"
+	StatusDone        Status = "DONE"
+	StatusCancelled   Status = "CANCELLED"
+)
+
+// transitions lists the statuses each status may move to.
+var transitions = map[Status][]Status{
+	StatusToBeStarted: {StatusInProgress, StatusCancelled},
+	StatusInProgress:  {StatusToBeStarted, StatusDone, StatusCancelled},
+	StatusDone:        {StatusInProgress},
+	StatusCancelled:   {StatusToBeStarted},
+}
+
+// ParseStatus validates and converts a string to a Status.
+func ParseStatus(s string) (Status, error) {
+	status := Status(s)
+	if _, ok := transitions[status]; !ok {
+		return "", apierror.Wrap(
+			apierror.ErrInvalidStatus,
+			http.StatusBadRequest,
+			fmt.Sprintf("unknown todo status %q", s),
+		)
+	}
+
+	return status, nil
+}
+
+// CanTransition reports whether a todo item may move from one status to another.
+func (s Status) CanTransition(to Status) bool {
+	return slices.Contains(transitions[s], to)
+}
+
+// checkTransition returns ErrInvalidTransition if the move from one status to another is not allowed.
+func checkTransition(from, to Status) error {
+	if !from.CanTransition(to) {
+		return apierror.Wrap(
+			apierror.ErrInvalidTransition,
+			http.StatusConflict,
+			fmt.Sprintf("cannot transition todo from %s to %s", from, to),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxSubtaskDepth is the number of levels of subtasks that Subtasks returns at most.
+const MaxSubtaskDepth = 10
+
+// Subtask is a todo item with its own subtasks, as far down as requested.
+type Subtask struct {
+	db.Item
+
+	Subtasks []Subtask `json:"subtasks,omitempty"`
+}
+
+// AddSubtask creates a new todo item as a subtask of an existing one, in the same scope.
+func (s *TodoService) AddSubtask(ctx context.Context, parentID int64, todo string, details ItemDetails) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.AddSubtask", attribute.Int64("todo.id", parentID))
+	defer func() { tracing.End(span, err) }()
+
+	parent, err := s.editable(ctx, parentID)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	return s.add(ctx, db.ItemScope(parent), parent.ID, todo, details)
+}
+
+// Subtasks returns the subtasks of a todo item down to depth levels below it, each with its own
+// subtasks. A depth of zero returns the direct subtasks only.
+func (s *TodoService) Subtasks(ctx context.Context, id int64, depth int) (_ []Subtask, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Subtasks", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	if depth == 0 {
+		depth = 1
+	}
+
+	if depth < 0 || depth > MaxSubtaskDepth {
+		return nil, apierror.Wrap(
+			apierror.ErrInvalidRequest,
+			http.StatusBadRequest,
+			fmt.Sprintf("depth must be between 1 and %d", MaxSubtaskDepth),
+		).WithDetails(apierror.FieldError{Field: "depth", Message: fmt.Sprintf("must be between 1 and %d", MaxSubtaskDepth)})
+	}
+
+	scope, err := s.scope(ctx, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown items are not found rather than without subtasks.
+	if _, err = s.db.GetItem(ctx, scope, id); err != nil {
+		return nil, err
+	}
+
+	items, err := s.db.ListSubtasks(ctx, scope, id, depth)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to get subtasks")
+	}
+
+	return subtaskTree(id, items), nil
+}
+
+// subtaskTree nests items under their parents and returns the subtasks of id, keeping the order
+// of items among siblings.
+func subtaskTree(id int64, items []db.Item) []Subtask {
+	children := make(map[int64][]db.Item)
+	for _, item := range items {
+		children[item.ParentID] = append(children[item.ParentID], item)
+	}
+
+	var build func(parentID int64) []Subtask
+	build = func(parentID int64) []Subtask {
+		subtasks := []Subtask{}
+		for _, item := range children[parentID] {
+			subtasks = append(subtasks, Subtask{Item: item, Subtasks: build(item.ID)})
+		}
+
+		return subtasks
+	}
+
+	return build(id)
+}
+
+// checkSubtasks rejects marking an item DONE while it has open subtasks, unless the rule is off.
+func (s *TodoService) checkSubtasks(item db.Item, to Status) error {
+	if !s.strictSubtasks || to != StatusDone || item.OpenSubtasks == 0 {
+		return nil
+	}
+
+	return apierror.Wrap(
+		apierror.ErrOpenSubtasks,
+		http.StatusConflict,
+		fmt.Sprintf("todo has %d open subtasks; finish or cancel them first", item.OpenSubtasks),
+	)
+}
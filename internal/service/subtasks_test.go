@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTodoService_Subtasks(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	ctx := context.Background()
+
+	trip, err := svc.Add(ctx, "Plan trip", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	packing, err := svc.AddSubtask(ctx, trip.ID, "Pack", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("AddSubtask() error = %v", err)
+	}
+
+	charger, err := svc.AddSubtask(ctx, packing.ID, "Pack charger", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("AddSubtask() error = %v", err)
+	}
+
+	if _, err = svc.AddSubtask(ctx, charger.ID+100, "Orphan", service.ItemDetails{}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("AddSubtask() with an unknown parent error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	// Another caller's todo cannot be a parent.
+	if _, err = svc.AddSubtask(as("mallory"), trip.ID, "Sneak in", service.ItemDetails{}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("AddSubtask() under another owner's todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	tests := []struct {
+		name      string
+		depth     int
+		wantErr   error
+		wantLevel int
+	}{
+		{name: "default depth", depth: 0, wantLevel: 1},
+		{name: "tree", depth: service.MaxSubtaskDepth, wantLevel: 2},
+		{name: "negative depth", depth: -1, wantErr: apierror.ErrInvalidRequest},
+		{name: "too deep", depth: service.MaxSubtaskDepth + 1, wantErr: apierror.ErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subtasks, err := svc.Subtasks(ctx, trip.ID, tt.depth)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Subtasks() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Subtasks() error = %v", err)
+			}
+
+			if len(subtasks) != 1 || subtasks[0].ID != packing.ID {
+				t.Fatalf("Subtasks() = %+v, want only %d", subtasks, packing.ID)
+			}
+
+			levels := 1
+			if len(subtasks[0].Subtasks) == 1 && subtasks[0].Subtasks[0].ID == charger.ID {
+				levels++
+			}
+			if levels != tt.wantLevel {
+				t.Errorf("Subtasks() returned %d levels, want %d", levels, tt.wantLevel)
+			}
+		})
+	}
+
+	if _, err = svc.Subtasks(ctx, charger.ID+100, 1); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Subtasks() of an unknown todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	for _, id := range []int64{trip.ID, packing.ID} {
+		if _, err = svc.Transition(ctx, id, "IN_PROGRESS"); err != nil {
+			t.Fatalf("Transition() error = %v", err)
+		}
+	}
+
+	if _, err = svc.Transition(ctx, packing.ID, "DONE"); !errors.Is(err, apierror.ErrOpenSubtasks) {
+		t.Errorf("Transition() to DONE with an open subtask error = %v, want %v", err, apierror.ErrOpenSubtasks)
+	}
+
+	done := "DONE"
+	if _, err = svc.Patch(ctx, packing.ID, service.ItemPatch{Status: &done}); !errors.Is(err, apierror.ErrOpenSubtasks) {
+		t.Errorf("Patch() to DONE with an open subtask error = %v, want %v", err, apierror.ErrOpenSubtasks)
+	}
+
+	if _, err = svc.Transition(ctx, charger.ID, "CANCELLED"); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	packed, err := svc.Transition(ctx, packing.ID, "DONE")
+	if err != nil {
+		t.Fatalf("Transition() to DONE with only cancelled subtasks error = %v", err)
+	}
+	if packed.Completion != nil {
+		t.Errorf("Transition() completion = %d, want nil with only cancelled subtasks", *packed.Completion)
+	}
+
+	got, err := svc.Get(ctx, trip.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.OpenSubtasks != 0 || got.Completion == nil || *got.Completion != 100 {
+		t.Errorf("Get() progress = %d open, %v%%, want 0 open, 100%%", got.OpenSubtasks, got.Completion)
+	}
+
+	if err = svc.Delete(ctx, trip.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err = svc.Get(ctx, charger.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() of a subtask of a deleted todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestTodoService_Subtasks_NotStrict(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()), service.WithStrictSubtasks(false))
+	ctx := context.Background()
+
+	parent, err := svc.Add(ctx, "Parent", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, err = svc.AddSubtask(ctx, parent.ID, "Child", service.ItemDetails{}); err != nil {
+		t.Fatalf("AddSubtask() error = %v", err)
+	}
+
+	if _, err = svc.Transition(ctx, parent.ID, "IN_PROGRESS"); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	if _, err = svc.Transition(ctx, parent.ID, "DONE"); err != nil {
+		t.Errorf("Transition() to DONE with an open subtask error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+)
+
+// Tag limits.
+const (
+	MaxTags      = 20
+	MaxTagLength = 50
+)
+
+// Tags returns the tags of the caller's todos, or of the service's list, with their usage
+// counts, most used first.
+func (s *TodoService) Tags(ctx context.Context) (_ []db.TagCount, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Tags")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := s.scope(ctx, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.db.ListTags(ctx, scope)
+	if err != nil {
+		return nil, apierror.Wrap(err, http.StatusInternalServerError, "failed to get tags")
+	}
+
+	return tags, nil
+}
+
+// normalizeTags trims and lower-cases tags, drops duplicates and sorts them, so that tags
+// differing only in case or surrounding space are the same tag. Errors name field as the invalid
+// field.
+func normalizeTags(tags []string, field string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > MaxTagLength {
+			return nil, invalidTags(field, fmt.Sprintf("must be between 1 and %d bytes", MaxTagLength))
+		}
+
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) > MaxTags {
+		return nil, invalidTags(field, fmt.Sprintf("must have at most %d tags", MaxTags))
+	}
+
+	return normalized, nil
+}
+
+// invalidTags returns the error for invalid tags given in field.
+func invalidTags(field, problem string) error {
+	return apierror.Wrap(apierror.ErrInvalidRequest, http.StatusBadRequest, "invalid "+field+": "+problem).
+		WithDetails(apierror.FieldError{Field: field, Message: problem})
+}
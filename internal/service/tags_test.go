@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTodoService_Tags(t *testing.T) {
+	svc := service.New(service.WithDB(db.NewMemory()))
+	ctx := context.Background()
+
+	groceries, err := svc.Add(ctx, "Groceries", service.ItemDetails{Tags: []string{" Shopping", "home", "shopping "}})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !slices.Equal(groceries.Tags, []string{"home", "shopping"}) {
+		t.Errorf("Add() tags = %v, want normalized [home shopping]", groceries.Tags)
+	}
+
+	if _, err = svc.Add(ctx, "Hardware", service.ItemDetails{Tags: []string{"shopping"}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tooMany := make([]string, service.MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+
+	errTests := []struct {
+		name string
+		tags []string
+	}{
+		{name: "empty tag", tags: []string{"home", " "}},
+		{name: "long tag", tags: []string{strings.Repeat("x", service.MaxTagLength+1)}},
+		{name: "too many tags", tags: tooMany},
+	}
+
+	// field returns the field the details of err name.
+	field := func(err error) string {
+		var apiErr *apierror.APIError
+		if !errors.As(err, &apiErr) || len(apiErr.Details) == 0 {
+			return ""
+		}
+
+		return apiErr.Details[0].Field
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Add(ctx, "Invalid", service.ItemDetails{Tags: tt.tags})
+			if !errors.Is(err, apierror.ErrInvalidRequest) || field(err) != "tags" {
+				t.Errorf("Add() error = %v, want %v on tags", err, apierror.ErrInvalidRequest)
+			}
+
+			_, err = svc.ListTodos(ctx, service.ListOptions{Tags: tt.tags})
+			if !errors.Is(err, apierror.ErrInvalidRequest) || field(err) != "tag" {
+				t.Errorf("ListTodos() error = %v, want %v on tag", err, apierror.ErrInvalidRequest)
+			}
+		})
+	}
+
+	listTests := []struct {
+		name string
+		opts service.ListOptions
+		want []string
+	}{
+		{name: "any", opts: service.ListOptions{Tags: []string{"HOME", "shopping"}}, want: []string{"Groceries", "Hardware"}},
+		{name: "all", opts: service.ListOptions{Tags: []string{"home", "shopping", "home"}, AllTags: true}, want: []string{"Groceries"}},
+	}
+
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := svc.ListTodos(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("ListTodos() error = %v", err)
+			}
+
+			var tasks []string
+			for _, item := range page.Items {
+				tasks = append(tasks, item.Task)
+			}
+			if !slices.Equal(tasks, tt.want) {
+				t.Errorf("ListTodos() = %v, want %v", tasks, tt.want)
+			}
+		})
+	}
+
+	// A patch without tags keeps them; one with tags replaces them.
+	task := "Weekly groceries"
+	patched, err := svc.Patch(ctx, groceries.ID, service.ItemPatch{Task: &task})
+	if err != nil || !slices.Equal(patched.Tags, groceries.Tags) {
+		t.Fatalf("Patch() = %v, %v, want the tags kept", patched.Tags, err)
+	}
+
+	tags := []string{"weekly"}
+	if _, err = svc.Patch(ctx, groceries.ID, service.ItemPatch{Tags: &tags}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+
+	counts, err := svc.Tags(ctx)
+	if err != nil {
+		t.Fatalf("Tags() error = %v", err)
+	}
+	want := []db.TagCount{{Tag: "shopping", Count: 1}, {Tag: "weekly", Count: 1}}
+	if !slices.Equal(counts, want) {
+		t.Errorf("Tags() = %v, want %v", counts, want)
+	}
+}
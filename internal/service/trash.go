@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultTrashRetention is how long deleted todos stay in the trash by default.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// WithTrashRetention sets how long deleted todos stay in the trash before PurgeTrash deletes
+// them for good. Zero keeps them until they are restored.
+func WithTrashRetention(retention time.Duration) Option {
+	return func(s *TodoService) {
+		s.trashRetention = retention
+	}
+}
+
+// Trash lists a filtered, sorted page of the caller's todo items in the trash, or of the
+// service's list.
+func (s *TodoService) Trash(ctx context.Context, opts ListOptions) (_ ListPage, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Trash")
+	defer func() { tracing.End(span, err) }()
+
+	return s.listScoped(ctx, opts, true)
+}
+
+// Restore moves a todo item of the caller out of the trash by its ID, together with the
+// subtasks that were deleted with it.
+func (s *TodoService) Restore(ctx context.Context, id int64) (_ db.Item, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.Restore", attribute.Int64("todo.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := s.scope(ctx, RoleEditor)
+	if err != nil {
+		return db.Item{}, err
+	}
+
+	item, err := s.db.RestoreItem(audited(ctx, db.ActionRestore), scope, id)
+	if err != nil {
+		return db.Item{}, apierror.Wrap(err, http.StatusInternalServerError, "failed to restore todo")
+	}
+
+	s.log(ctx).InfoContext(ctx, "todo restored", "todo_id", id)
+
+	return item, nil
+}
+
+// PurgeTrash deletes the todo items of every owner that have been in the trash for longer than
+// the retention period, and returns how many were deleted. It deletes nothing if the retention
+// period is zero.
+func (s *TodoService) PurgeTrash(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "TodoService.PurgeTrash")
+	defer func() { tracing.End(span, err) }()
+
+	if s.trashRetention <= 0 {
+		return 0, nil
+	}
+
+	purged, err := s.db.PurgeItems(ctx, s.now().Add(-s.trashRetention))
+	if err != nil {
+		return 0, apierror.Wrap(err, http.StatusInternalServerError, "failed to purge trash")
+	}
+
+	return purged, nil
+}
+
+// RunTrashPurge calls PurgeTrash every interval until ctx is cancelled, logging failures.
+// It returns at once if the interval or the retention period is not positive.
+func (s *TodoService) RunTrashPurge(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || s.trashRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := s.PurgeTrash(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to purge trash", "error", err)
+
+			continue
+		}
+
+		if purged > 0 {
+			s.logger.InfoContext(ctx, "purged trash", "todos", purged)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/service"
+)
+
+func TestTodoService_Trash(t *testing.T) {
+	now := time.Now()
+	svc := service.New(
+		service.WithDB(db.NewMemory()),
+		service.WithTrashRetention(time.Hour),
+		service.WithClock(func() time.Time { return now }),
+	)
+	ctx := context.Background()
+
+	item, err := svc.Add(ctx, "Call the bank", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if err = svc.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err = svc.Get(ctx, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Get() of a deleted todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	trash, err := svc.Trash(ctx, service.ListOptions{})
+	if err != nil || len(trash.Items) != 1 || trash.Items[0].ID != item.ID {
+		t.Fatalf("Trash() = %v, %v, want only %d", trash.Items, err, item.ID)
+	}
+
+	if other, otherErr := svc.Trash(as("mallory"), service.ListOptions{}); otherErr != nil || len(other.Items) != 0 {
+		t.Errorf("Trash() of another caller = %v, %v, want empty", other.Items, otherErr)
+	}
+
+	if _, err = svc.Restore(as("mallory"), item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Restore() by another caller error = %v, want %v", err, apierror.ErrNotFound)
+	}
+
+	restored, err := svc.Restore(ctx, item.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Restore() = %+v, %v, want it outside the trash", restored, err)
+	}
+
+	if err = svc.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		after      time.Duration
+		wantPurged int64
+	}{
+		{name: "within retention", after: 0, wantPurged: 0},
+		{name: "past retention", after: 2 * time.Hour, wantPurged: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = time.Now().Add(tt.after)
+
+			purged, err := svc.PurgeTrash(ctx)
+			if err != nil {
+				t.Fatalf("PurgeTrash() error = %v", err)
+			}
+			if purged != tt.wantPurged {
+				t.Errorf("PurgeTrash() = %d, want %d", purged, tt.wantPurged)
+			}
+		})
+	}
+
+	if _, err = svc.Restore(ctx, item.ID); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Restore() of a purged todo error = %v, want %v", err, apierror.ErrNotFound)
+	}
+}
+
+func TestTodoService_PurgeTrash_KeepForever(t *testing.T) {
+	store := db.NewMemory()
+	svc := service.New(
+		service.WithDB(store),
+		service.WithTrashRetention(0),
+		service.WithClock(func() time.Time { return time.Now().Add(24 * 365 * time.Hour) }),
+	)
+	ctx := context.Background()
+
+	item, err := svc.Add(ctx, "Keep", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if err = svc.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if purged, purgeErr := svc.PurgeTrash(ctx); purgeErr != nil || purged != 0 {
+		t.Errorf("PurgeTrash() = %d, %v, want nothing purged", purged, purgeErr)
+	}
+
+	// RunTrashPurge returns at once when purging is disabled.
+	svc.RunTrashPurge(ctx, time.Millisecond)
+
+	if _, err = svc.Restore(ctx, item.ID); err != nil {
+		t.Errorf("Restore() error = %v", err)
+	}
+}
+
+func TestTodoService_RunTrashPurge(t *testing.T) {
+	svc := service.New(
+		service.WithDB(db.NewMemory()),
+		service.WithTrashRetention(time.Nanosecond),
+	)
+
+	item, err := svc.Add(context.Background(), "Purge me", service.ItemDetails{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if err = svc.Delete(context.Background(), item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.RunTrashPurge(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		trash, trashErr := svc.Trash(context.Background(), service.ListOptions{})
+		if trashErr != nil {
+			t.Fatalf("Trash() error = %v", trashErr)
+		}
+
+		if len(trash.Items) == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("RunTrashPurge() did not purge the trash")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this application.
+const instrumentationName = "github.com/brkcnr/golandworks-api"
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// Spans are exported as configured; with config.TraceExporterNone only incoming trace
+// context is propagated. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg config.TraceConfig, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch cfg.Exporter {
+	case config.TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case config.TraceExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span and ends it. Only server errors mark the span as failed;
+// client errors such as a missing todo are recorded but leave the status unset.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		if apierror.Response(err, "").Code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
+
+// ObserveStore starts a span for a Storer operation. It has the signature of a db.Observer.
+func ObserveStore(ctx context.Context, op string) (context.Context, func(error)) {
+	ctx, span := Start(ctx, "db."+op, attribute.String("db.operation", op))
+
+	return ctx, func(err error) {
+		End(span, err)
+	}
+}
+
+// QueryTracer is a pgx.QueryTracer and pgx.BatchTracer that records a span for every SQL query,
+// and one for every batch with an event for each of its queries.
+type QueryTracer struct{}
+
+// Compile time proof.
+var (
+	_ pgx.QueryTracer = QueryTracer{}
+	_ pgx.BatchTracer = QueryTracer{}
+)
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = otel.Tracer(instrumentationName).Start(ctx, "pgx.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	recordQueryError(span, data.Err)
+	span.End()
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (QueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = otel.Tracer(instrumentationName).Start(ctx, "pgx.batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.Int("db.batch.size", data.Batch.Len()),
+		),
+	)
+
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.
+func (QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("pgx.batch.query", trace.WithAttributes(
+		semconv.DBQueryText(data.SQL),
+		attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()),
+	))
+	recordQueryError(span, data.Err)
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (QueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	span := trace.SpanFromContext(ctx)
+	recordQueryError(span, data.Err)
+	span.End()
+}
+
+// recordQueryError marks span as failed with err, if set.
+func recordQueryError(span trace.Span, err error) {
+	// No rows is how lookups report a missing todo, not a failed query.
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,197 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider that records ended spans for the rest of the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantEvents int
+	}{
+		{
+			name:       "success",
+			err:        nil,
+			wantStatus: codes.Unset,
+			wantEvents: 0,
+		},
+		{
+			name:       "client error",
+			err:        apierror.ErrNotFound,
+			wantStatus: codes.Unset,
+			wantEvents: 1,
+		},
+		{
+			name:       "server error",
+			err:        apierror.Wrap(context.DeadlineExceeded, http.StatusInternalServerError, "failed to read todos"),
+			wantStatus: codes.Error,
+			wantEvents: 1,
+		},
+		{
+			name:       "plain error",
+			err:        context.Canceled,
+			wantStatus: codes.Error,
+			wantEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record(t)
+
+			_, span := tracing.Start(context.Background(), "test")
+			tracing.End(span, tt.err)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+
+			if got := spans[0].Status().Code; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+
+			if got := len(spans[0].Events()); got != tt.wantEvents {
+				t.Errorf("got %d events, want %d", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestObserveStore(t *testing.T) {
+	recorder := record(t)
+
+	ctx, parent := tracing.Start(context.Background(), "TodoService.Add")
+	store := db.Observe(db.NewMemory(), tracing.ObserveStore)
+
+	if _, err := store.InsertItem(ctx, db.Item{Task: "Trace me", Status: "TO_BE_STARTED"}); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+	tracing.End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	child := spans[0]
+	if child.Name() != "db."+db.OpInsertItem {
+		t.Errorf("span name = %q, want %q", child.Name(), "db."+db.OpInsertItem)
+	}
+
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("storage span is not a child of the service span")
+	}
+}
+
+func TestQueryTracer_Batch(t *testing.T) {
+	recorder := record(t)
+
+	batch := &pgx.Batch{}
+	batch.Queue("INSERT INTO todo_items (task) VALUES ($1)", "first")
+	batch.Queue("INSERT INTO todo_items (task) VALUES ($1)", "second")
+
+	// pgx calls the tracer like this for SendBatch; the second insert fails.
+	var tracer tracing.QueryTracer
+	ctx := tracer.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{Batch: batch})
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: batch.QueuedQueries[0].SQL})
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: batch.QueuedQueries[1].SQL, Err: errors.New("duplicate key")})
+	tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "pgx.batch" {
+		t.Errorf("span name = %q, want %q", span.Name(), "pgx.batch")
+	}
+
+	queries := 0
+	for _, event := range span.Events() {
+		if event.Name == "pgx.batch.query" {
+			queries++
+		}
+	}
+	if queries != 2 {
+		t.Errorf("got %d query events, want 2", queries)
+	}
+
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want %v", span.Status().Code, codes.Error)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	tests := []struct {
+		name       string
+		exporter   string
+		wantOutput bool
+	}{
+		{
+			name:       "none",
+			exporter:   config.TraceExporterNone,
+			wantOutput: false,
+		},
+		{
+			name:       "stdout",
+			exporter:   config.TraceExporterStdout,
+			wantOutput: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cfg := config.TraceConfig{Exporter: tt.exporter, ServiceName: "test", SampleRatio: 1}
+
+			shutdown, err := tracing.Setup(context.Background(), cfg, &out)
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+
+			_, span := tracing.Start(context.Background(), "test")
+			tracing.End(span, nil)
+
+			if err := shutdown(context.Background()); err != nil {
+				t.Fatalf("shutdown() error = %v", err)
+			}
+
+			if got := out.Len() > 0; got != tt.wantOutput {
+				t.Errorf("wrote spans = %v, want %v", got, tt.wantOutput)
+			}
+		})
+	}
+}
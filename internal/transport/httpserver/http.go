@@ -1,68 +1,338 @@
 package httpserver
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/config"
 	"github.com/brkcnr/golandworks-api/internal/handler"
+	"github.com/brkcnr/golandworks-api/internal/metrics"
 	"github.com/brkcnr/golandworks-api/internal/service"
 )
 
+// Defaults used when no configuration is given.
 const (
+	defaultAddr       = ":8080"
 	readTimeout       = 15 * time.Second
 	writeTimeout      = 15 * time.Second
 	idleTimeout       = 60 * time.Second
 	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 30 * time.Second
+	drainDelay        = 5 * time.Second
+	readinessTimeout  = 2 * time.Second
 )
 
 // Server is a HTTP server.
 type Server struct {
-	mux *http.ServeMux
+	mux         *http.ServeMux
+	handler     http.Handler
+	cfg         config.HTTPConfig
+	logger      *slog.Logger
+	middlewares []Middleware
+	health      *handler.Health
+	checks      []handler.HealthOption
+	metrics     *metrics.Metrics
+	auth        *auth.Authenticator
+	identity    string
+}
+
+// Option is a function that configures a Server.
+type Option func(*Server)
+
+// WithConfig sets the listen address and timeouts.
+func WithConfig(cfg config.HTTPConfig) Option {
+	return func(s *Server) {
+		s.cfg = cfg
+	}
+}
+
+// WithLogger sets the logger that request loggers are derived from.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithMiddleware adds middlewares that run inside the built-in ones, just before routing.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, middlewares...)
+	}
+}
+
+// WithReadinessCheck adds a dependency check to the readiness endpoint.
+func WithReadinessCheck(name string, check handler.Check) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, handler.WithCheck(name, check))
+	}
+}
+
+// WithMetrics records request metrics and serves all metrics at GET /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithAuth requires valid credentials for the todo routes. Health and metrics
+// endpoints stay open so probes and scrapers need no credentials.
+func WithAuth(authenticator *auth.Authenticator) Option {
+	return func(s *Server) {
+		s.auth = authenticator
+	}
+}
+
+// WithIdentityHeader identifies callers of the todo routes by the named header instead of
+// authenticating them. Such callers only hold the read and write scopes, so the admin routes
+// always answer 403. It is ignored if WithAuth is also given.
+func WithIdentityHeader(header string) Option {
+	return func(s *Server) {
+		s.identity = header
+	}
 }
 
 // New creates a new HTTP server.
-func New(todoSvc *service.TodoService) *Server {
+func New(todoSvc *service.TodoService, opts ...Option) *Server {
 	mux := http.NewServeMux()
 
+	server := &Server{
+		mux: mux,
+
+		cfg: config.HTTPConfig{
+			Addr: defaultAddr,
+
+			ReadTimeout: readTimeout,
+
+			WriteTimeout: writeTimeout,
+
+			IdleTimeout: idleTimeout,
+
+			ReadHeaderTimeout: readHeaderTimeout,
+
+			ShutdownTimeout: shutdownTimeout,
+
+			DrainDelay: drainDelay,
+
+			ReadinessTimeout: readinessTimeout,
+
+			TrustRequestID: true,
+
+			AccessLog: true,
+		},
+
+		logger: slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
 	todoHandler := handler.New(
 
 		handler.WithTodoService(todoSvc),
 
-		handler.WithLogger(log.New(os.Stdout, "TODO-API: ", log.LstdFlags)),
+		handler.WithLogger(server.logger),
 	)
 
-	mux.HandleFunc("GET /todo", todoHandler.ListTodos)
+	server.health = handler.NewHealth(append(server.checks,
+		handler.WithCheckTimeout(server.cfg.ReadinessTimeout),
 
-	mux.HandleFunc("POST /todo", todoHandler.Add)
+		handler.WithHealthLogger(server.logger),
+	)...)
 
-	mux.HandleFunc("GET /search", todoHandler.Search)
+	mux.HandleFunc("GET /healthz", server.health.Live)
 
-	return &Server{
-		mux: mux,
+	mux.HandleFunc("GET /readyz", server.health.Ready)
+
+	if server.metrics != nil {
+		mux.Handle("GET /metrics", server.metrics.Handler())
+	}
+
+	server.handle("GET /todo", auth.ScopeRead, todoHandler.ListTodos)
+
+	server.handle("POST /todo", auth.ScopeWrite, todoHandler.Add)
+
+	server.handle("POST /todo/batch", auth.ScopeWrite, todoHandler.Batch)
+
+	server.handle("GET /todo/{id}", auth.ScopeRead, todoHandler.Get)
+
+	server.handle("PUT /todo/{id}", auth.ScopeWrite, todoHandler.Update)
+
+	server.handle("PATCH /todo/{id}", auth.ScopeWrite, todoHandler.Patch)
+
+	server.handle("DELETE /todo/{id}", auth.ScopeWrite, todoHandler.Delete)
+
+	server.handle("POST /todo/{id}/transitions", auth.ScopeWrite, todoHandler.Transition)
+
+	server.handle("GET /todo/{id}/children", auth.ScopeRead, todoHandler.Subtasks)
+
+	server.handle("POST /todo/{id}/children", auth.ScopeWrite, todoHandler.AddSubtask)
+
+	server.handle("POST /todo/{id}/restore", auth.ScopeWrite, todoHandler.Restore)
+
+	server.handle("GET /todo/{id}/history", auth.ScopeRead, todoHandler.History)
+
+	server.handle("GET /trash", auth.ScopeRead, todoHandler.Trash)
+
+	server.handle("GET /search", auth.ScopeRead, todoHandler.Search)
+
+	server.handle("GET /tags", auth.ScopeRead, todoHandler.Tags)
+
+	server.handle("GET /admin/todo", auth.ScopeAdmin, todoHandler.ListAllTodos)
+
+	server.handle("GET /admin/todo/{id}/history", auth.ScopeAdmin, todoHandler.AllHistory)
+
+	server.handle("GET /lists", auth.ScopeRead, todoHandler.Lists)
+
+	server.handle("POST /lists", auth.ScopeWrite, todoHandler.CreateList)
+
+	server.handle("GET /lists/{list}", auth.ScopeRead, todoHandler.GetList)
+
+	server.handle("DELETE /lists/{list}", auth.ScopeWrite, todoHandler.DeleteList)
+
+	server.handle("GET /lists/{list}/members", auth.ScopeRead, todoHandler.Members)
+
+	server.handle("PUT /lists/{list}/members/{member}", auth.ScopeWrite, todoHandler.SetMember)
+
+	server.handle("DELETE /lists/{list}/members/{member}", auth.ScopeWrite, todoHandler.RemoveMember)
+
+	server.handle("GET /lists/{list}/todos", auth.ScopeRead, todoHandler.ListTodos)
+
+	server.handle("POST /lists/{list}/todos", auth.ScopeWrite, todoHandler.Add)
+
+	server.handle("POST /lists/{list}/todos/batch", auth.ScopeWrite, todoHandler.Batch)
+
+	server.handle("GET /lists/{list}/todos/{id}", auth.ScopeRead, todoHandler.Get)
+
+	server.handle("PUT /lists/{list}/todos/{id}", auth.ScopeWrite, todoHandler.Update)
+
+	server.handle("PATCH /lists/{list}/todos/{id}", auth.ScopeWrite, todoHandler.Patch)
+
+	server.handle("DELETE /lists/{list}/todos/{id}", auth.ScopeWrite, todoHandler.Delete)
+
+	server.handle("POST /lists/{list}/todos/{id}/transitions", auth.ScopeWrite, todoHandler.Transition)
+
+	server.handle("GET /lists/{list}/todos/{id}/children", auth.ScopeRead, todoHandler.Subtasks)
+
+	server.handle("POST /lists/{list}/todos/{id}/children", auth.ScopeWrite, todoHandler.AddSubtask)
+
+	server.handle("POST /lists/{list}/todos/{id}/restore", auth.ScopeWrite, todoHandler.Restore)
+
+	server.handle("GET /lists/{list}/todos/{id}/history", auth.ScopeRead, todoHandler.History)
+
+	server.handle("GET /lists/{list}/trash", auth.ScopeRead, todoHandler.Trash)
+
+	server.handle("GET /lists/{list}/tags", auth.ScopeRead, todoHandler.Tags)
+
+	server.handler = Chain(mux, server.chain()...)
+
+	return server
+}
+
+// handle registers a route that requires scope when callers are authenticated or identified.
+// Without either, callers only hold the read and write scopes, so routes needing any other
+// scope answer 403.
+func (s *Server) handle(pattern, scope string, h http.HandlerFunc) {
+	switch {
+	case s.auth != nil:
+		s.mux.Handle(pattern, Authenticate(s.auth, scope, s.logger)(h))
+	case s.identity != "":
+		s.mux.Handle(pattern, Identity(s.identity, scope, s.logger)(h))
+	case scope != auth.ScopeRead && scope != auth.ScopeWrite:
+		s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, r, s.logger, apierror.Wrap(apierror.ErrForbidden, http.StatusForbidden,
+				fmt.Sprintf("missing scope %s", scope)))
+		})
+	default:
+		s.mux.HandleFunc(pattern, h)
+	}
+}
+
+// chain returns the middlewares wrapping the routes, outermost first.
+func (s *Server) chain() []Middleware {
+	middlewares := []Middleware{Tracing(s.mux), RequestID(s.cfg.TrustRequestID)}
+
+	if s.cfg.AccessLog {
+		middlewares = append(middlewares, AccessLog(s.logger, s.mux))
+	}
+
+	if s.metrics != nil {
+		middlewares = append(middlewares, RequestMetrics(s.metrics, s.mux))
+	}
+
+	middlewares = append(middlewares, Recover(s.logger), CORS(s.cfg.CORS))
+
+	return append(middlewares, s.middlewares...)
+}
+
+// Serve serves the HTTP server until ctx is cancelled, then shuts down gracefully,
+// giving in-flight requests up to the configured shutdown timeout to finish.
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to start HTTP server")
 	}
+
+	return s.ServeListener(ctx, listener)
 }
 
-// Serve serves the HTTP server.
-func (s *Server) Serve() error {
+// ServeListener is like Serve but accepts connections on an existing listener,
+// ignoring the configured address. The listener is closed on return.
+func (s *Server) ServeListener(ctx context.Context, listener net.Listener) error {
 	srv := &http.Server{
-		Addr: ":8080",
+		Handler: s.handler,
 
-		Handler: s.mux,
+		ReadTimeout: s.cfg.ReadTimeout,
 
-		ReadTimeout: readTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
 
-		WriteTimeout: writeTimeout,
+		IdleTimeout: s.cfg.IdleTimeout,
 
-		IdleTimeout: idleTimeout,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+	}
 
-		ReadHeaderTimeout: readHeaderTimeout,
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return apierror.Wrap(err, http.StatusInternalServerError, "HTTP server stopped unexpectedly")
+	case <-ctx.Done():
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		return apierror.Wrap(err, http.StatusInternalServerError, "failed to start HTTP server")
+	// Fail readiness first and keep serving for a while, so load balancers drain this instance
+	// before it stops accepting connections.
+	s.health.SetDraining(true)
+	s.logger.Info("draining before shutdown", "delay", s.cfg.DrainDelay)
+
+	drain := time.NewTimer(s.cfg.DrainDelay)
+	defer drain.Stop()
+
+	select {
+	case err := <-serveErr:
+		return apierror.Wrap(err, http.StatusInternalServerError, "HTTP server stopped unexpectedly")
+	case <-drain.C:
+	}
+
+	// The parent context is already cancelled, so the grace period needs a fresh one.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return apierror.Wrap(err, http.StatusInternalServerError, "failed to shut down HTTP server gracefully")
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return apierror.Wrap(err, http.StatusInternalServerError, "HTTP server stopped unexpectedly")
 	}
 
 	return nil
@@ -70,5 +340,5 @@ func (s *Server) Serve() error {
 
 // ServeHTTP implements the http.Handler interface.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
@@ -1,21 +1,34 @@
 package httpserver_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/metrics"
+	"github.com/brkcnr/golandworks-api/internal/requestid"
 	"github.com/brkcnr/golandworks-api/internal/service"
 	"github.com/brkcnr/golandworks-api/internal/transport/httpserver"
 )
 
 func TestNew(t *testing.T) {
 	// Initialize dependencies
-	todoSvc := &service.TodoService{}
-	
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+
 	// Create new server
 	server := httpserver.New(todoSvc)
-	
+
 	// Test cases for different endpoints
 	tests := []struct {
 		name           string
@@ -26,25 +39,49 @@ func TestNew(t *testing.T) {
 		{
 			name:           "GET /todo should return OK",
 			method:         "GET",
-			path:          "/todo",
+			path:           "/todo",
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:           "POST /todo should return OK",
+			name:           "POST /todo without body should return BadRequest",
 			method:         "POST",
-			path:          "/todo",
+			path:           "/todo",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "GET /todo/{id} for missing todo should return NotFound",
+			method:         "GET",
+			path:           "/todo/1",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "GET /search without query should return BadRequest",
+			method:         "GET",
+			path:           "/search",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "GET /admin/todo without auth should return Forbidden",
+			method:         "GET",
+			path:           "/admin/todo",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "GET /healthz should return OK",
+			method:         "GET",
+			path:           "/healthz",
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:           "GET /search should return OK",
+			name:           "GET /readyz without dependencies should return OK",
 			method:         "GET",
-			path:          "/search",
+			path:           "/readyz",
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "Invalid path should return NotFound",
 			method:         "GET",
-			path:          "/invalid",
+			path:           "/invalid",
 			expectedStatus: http.StatusNotFound,
 		},
 	}
@@ -53,17 +90,370 @@ func TestNew(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create request
 			req := httptest.NewRequest(tt.method, tt.path, nil)
-			
+
 			// Create response recorder
 			w := httptest.NewRecorder()
-			
+
 			// Serve the request
 			server.ServeHTTP(w, req)
-			
+
 			// Check status code
 			if got := w.Code; got != tt.expectedStatus {
 				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, got)
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestNew_Auth(t *testing.T) {
+	keyHash := sha256.Sum256([]byte("ci-key"))
+	authenticator, err := auth.New(config.AuthConfig{APIKeys: []config.APIKey{{Name: "ci", Hash: keyHash[:]}}})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+	server := httpserver.New(todoSvc, httpserver.WithAuth(authenticator), httpserver.WithMetrics(metrics.New()))
+
+	tests := []struct {
+		name           string
+		path           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{
+			name:           "todo routes require credentials",
+			path:           "/todo",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "todo routes accept an API key",
+			path:           "/todo",
+			apiKey:         "ci-key",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "admin routes need the admin scope",
+			path:           "/admin/todo",
+			apiKey:         "ci-key",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "search requires credentials",
+			path:           "/search?q=walk",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "liveness stays open",
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "readiness stays open",
+			path:           "/readyz",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "metrics stay open",
+			path:           "/metrics",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if got := w.Code; got != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, got)
+			}
+		})
+	}
+}
+
+func TestNew_IdentityHeader(t *testing.T) {
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+	server := httpserver.New(todoSvc, httpserver.WithIdentityHeader("X-User-ID"))
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user != "" {
+			req.Header.Set("X-User-ID", user)
+		}
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		return w
+	}
+
+	for _, user := range []string{"alice", "bob"} {
+		if w := do(http.MethodPost, "/todo", user, `{"item":"Water the plants"}`); w.Code != http.StatusCreated {
+			t.Fatalf("POST /todo as %s returned %d, want %d", user, w.Code, http.StatusCreated)
+		}
+	}
+
+	if w := do(http.MethodGet, "/todo", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /todo without identity returned %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w := do(http.MethodGet, "/todo/1", "bob", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET of another user's todo returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w := do(http.MethodGet, "/todo", "alice", "")
+	var page service.ListPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].OwnerID != "alice" {
+		t.Errorf("GET /todo as alice = %v, want only alice's todo", page.Items)
+	}
+
+	if w := do(http.MethodGet, "/admin/todo", "alice", ""); w.Code != http.StatusForbidden {
+		t.Errorf("GET /admin/todo with an identity header returned %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if w := do(http.MethodGet, "/healthz", "", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /healthz without identity returned %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestNew_Lists(t *testing.T) {
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+	server := httpserver.New(todoSvc, httpserver.WithIdentityHeader("X-User-ID"))
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User-ID", user)
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		return w
+	}
+
+	tests := []struct {
+		method   string
+		path     string
+		user     string
+		body     string
+		wantCode int
+	}{
+		{http.MethodPost, "/lists", "alice", `{"name":"Groceries"}`, http.StatusCreated},
+		{http.MethodPut, "/lists/1/members/bob", "alice", `{"role":"editor"}`, http.StatusOK},
+		{http.MethodPost, "/lists/1/todos", "bob", `{"item":"Milk"}`, http.StatusCreated},
+		{http.MethodPost, "/lists/1/todos/1/transitions", "bob", `{"status":"IN_PROGRESS"}`, http.StatusOK},
+		{http.MethodPost, "/lists/1/todos/1/children", "bob", `{"item":"Oat milk"}`, http.StatusCreated},
+		{http.MethodGet, "/lists/1/todos/1/children", "alice", "", http.StatusOK},
+		{http.MethodGet, "/lists/1/todos/1/children", "carol", "", http.StatusNotFound},
+		{http.MethodDelete, "/lists/1/todos/2", "bob", "", http.StatusNoContent},
+		{http.MethodGet, "/lists/1/trash", "alice", "", http.StatusOK},
+		{http.MethodPost, "/lists/1/todos/2/restore", "bob", "", http.StatusOK},
+		{http.MethodGet, "/lists/1/todos/2/history", "alice", "", http.StatusOK},
+		{http.MethodGet, "/lists/1/todos/2/history", "carol", "", http.StatusNotFound},
+		{http.MethodPost, "/lists/1/todos/batch", "bob", `{"ops":[{"op":"create","item":"Eggs"},{"op":"delete","id":1}]}`, http.StatusOK},
+		{http.MethodPost, "/lists/1/todos/batch", "carol", `{"ops":[{"op":"create","item":"Bread"}]}`, http.StatusNotFound},
+		{http.MethodGet, "/lists/1/todos", "carol", "", http.StatusNotFound},
+		{http.MethodGet, "/todo/1", "bob", "", http.StatusNotFound},
+		{http.MethodGet, "/lists/1/members", "bob", "", http.StatusOK},
+		{http.MethodDelete, "/lists/1", "bob", "", http.StatusForbidden},
+		{http.MethodDelete, "/lists/1", "alice", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		if w := do(tt.method, tt.path, tt.user, tt.body); w.Code != tt.wantCode {
+			t.Errorf("%s %s as %s returned %d, want %d: %s", tt.method, tt.path, tt.user, w.Code, tt.wantCode, w.Body)
+		}
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	server := httpserver.New(service.New(service.WithDB(db.NewMemory())), httpserver.WithLogger(logger))
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/1", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestid.Header); got != "req-1" {
+		t.Errorf("Expected request ID header %q, got %q", "req-1", got)
+	}
+
+	var completed map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Failed to decode log record %q: %v", line, err)
+		}
+
+		if record["request_id"] != "req-1" {
+			t.Errorf("Expected every record to carry the request ID, got %v", record)
+		}
+
+		if record["msg"] == "request completed" {
+			completed = record
+		}
+	}
+
+	if completed == nil {
+		t.Fatalf("Expected a request completed record, got %s", logs.String())
+	}
+
+	if completed["method"] != http.MethodGet || completed["route"] != "GET /todo/{id}" ||
+		completed["status"] != float64(http.StatusNotFound) || completed["latency"] == nil {
+		t.Errorf("Unexpected request completed record: %v", completed)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	m := metrics.New()
+	server := httpserver.New(service.New(service.WithDB(db.NewMemory())), httpserver.WithMetrics(m))
+
+	for _, path := range []string{"/todo", "/todo/1", "/todo/2", "/missing"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	for _, expected := range []string{
+		`golandworks_http_requests_total{route="GET /todo",status="200"} 1`,
+		`golandworks_http_requests_total{route="GET /todo/{id}",status="404"} 2`,
+		`golandworks_http_requests_total{route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Expected metrics to contain %q", expected)
+		}
+	}
+}
+
+func TestServeListener_GracefulShutdown(t *testing.T) {
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+	server := httpserver.New(todoSvc, httpserver.WithConfig(config.HTTPConfig{
+		ReadTimeout:     time.Second,
+		WriteTimeout:    time.Second,
+		ShutdownTimeout: time.Second,
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ServeListener(ctx, listener)
+	}()
+
+	url := "http://" + listener.Addr().String() + "/todo"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET /todo failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+
+	select {
+	case err = <-served:
+		if err != nil {
+			t.Errorf("ServeListener() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeListener() did not return after the context was cancelled")
+	}
+
+	if resp, err = http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("Expected requests to fail after shutdown")
+	}
+}
+
+func TestServeListener_DrainsBeforeShutdown(t *testing.T) {
+	todoSvc := service.New(service.WithDB(db.NewMemory()))
+	server := httpserver.New(todoSvc,
+		httpserver.WithConfig(config.HTTPConfig{
+			ShutdownTimeout: time.Second,
+			DrainDelay:      time.Second,
+		}),
+		httpserver.WithReadinessCheck("database", func(context.Context) error { return nil }),
+	)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ServeListener(ctx, listener)
+	}()
+
+	url := "http://" + listener.Addr().String()
+
+	readyStatus := func() int {
+		resp, getErr := http.Get(url + "/readyz")
+		if getErr != nil {
+			t.Fatalf("GET /readyz failed: %v", getErr)
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if got := readyStatus(); got != http.StatusOK {
+		t.Fatalf("Expected status code %d before shutdown, got %d", http.StatusOK, got)
+	}
+
+	cancel()
+
+	// Readiness fails during the drain delay while other requests are still served.
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for readyStatus() != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected readiness to fail after shutdown started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := http.Get(url + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz during drain failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d during drain, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	select {
+	case err = <-served:
+		if err != nil {
+			t.Errorf("ServeListener() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeListener() did not return after the drain delay")
+	}
+}
@@ -0,0 +1,359 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+	"github.com/brkcnr/golandworks-api/internal/metrics"
+	"github.com/brkcnr/golandworks-api/internal/requestid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by the HTTP server.
+const tracerName = "github.com/brkcnr/golandworks-api/internal/transport/httpserver"
+
+// maxHeaderValueLength bounds request and caller IDs accepted from clients.
+const maxHeaderValueLength = 128
+
+// Middleware wraps a http.Handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the middlewares. The first middleware is the outermost,
+// so it sees the request first and the response last.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// statusRecorder records the status code and body size written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and writes it.
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the body size and writes b, sending an implicit 200 status first.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Tracing starts a server span for every request, continuing any trace from the incoming
+// W3C traceparent header. Spans are named after the route pattern from mux.
+func Tracing(mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			_, route := mux.Handler(r)
+			name := route
+			if name == "" {
+				name = r.Method
+			}
+
+			ctx, span := otel.Tracer(tracerName).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+			if recorder.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(recorder.status))
+			}
+		})
+	}
+}
+
+// RequestID gives every request an ID, stores it in the request context and echoes it
+// in the X-Request-ID response header. If trustClient is set, a valid ID sent by the
+// client is kept so that requests can be traced across services.
+func RequestID(trustClient bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !trustClient || !validHeaderValue(id) {
+				id = requestid.New()
+			}
+
+			w.Header().Set(requestid.Header, id)
+			next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+		})
+	}
+}
+
+// validHeaderValue reports whether a client-supplied ID is safe to log and echo.
+func validHeaderValue(id string) bool {
+	if id == "" || len(id) > maxHeaderValueLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AccessLog gives every request a logger carrying its request ID, method, route and trace ID,
+// and logs the status, size and latency once the request completes.
+// Routes are resolved against mux so that logs group requests by pattern, not by path.
+func AccessLog(logger *slog.Logger, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			_, route := mux.Handler(r)
+			requestLogger := logger.With(
+				"request_id", requestid.FromContext(r.Context()),
+				"method", r.Method,
+				"route", route,
+			)
+
+			if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+				requestLogger = requestLogger.With("trace_id", spanContext.TraceID().String())
+			}
+
+			ctx := logging.NewContext(r.Context(), requestLogger)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			requestLogger.LogAttrs(ctx, slog.LevelInfo, "request completed",
+				slog.Int("status", recorder.status),
+				slog.Int("size", recorder.size),
+				slog.Duration("latency", time.Since(start)),
+			)
+		})
+	}
+}
+
+// RequestMetrics records the count and latency of every request, labeled by the route
+// pattern from mux and the status code.
+func RequestMetrics(m *metrics.Metrics, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			_, route := mux.Handler(r)
+			m.ObserveRequest(route, recorder.status, time.Since(start))
+		})
+	}
+}
+
+// Recover turns a panic in a handler into an apierror.ErrInternalServer JSON response
+// and logs it, so one bad request cannot take down the server.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				if recovered == http.ErrAbortHandler {
+					// The handler aborted on purpose; let net/http close the connection.
+					panic(recovered)
+				}
+
+				logging.FromContext(r.Context(), logger).ErrorContext(r.Context(), "panic while serving request",
+					"panic", recovered)
+
+				if recorder.wroteHeader {
+					// The response has started, so the status can no longer be changed.
+					return
+				}
+
+				writeError(w, r, logger, apierror.ErrInternalServer)
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// Authenticate rejects requests without valid credentials with 401, and requests whose
+// principal lacks scope with 403. The principal is stored in the request context, and the
+// request logger and span are tagged with its subject.
+func Authenticate(authenticator *auth.Authenticator, scope string, logger *slog.Logger) Middleware {
+	return requirePrincipal(authenticator.Authenticate, `Bearer realm="golandworks-api"`, scope, logger)
+}
+
+// Identity identifies callers by the value of header, which a trusted proxy must set after
+// authenticating them. Callers get the read and write scopes, so requests needing any other
+// scope are rejected with 403. Requests without the header are rejected with 401.
+func Identity(header, scope string, logger *slog.Logger) Middleware {
+	return requirePrincipal(func(r *http.Request) (auth.Principal, error) {
+		subject := r.Header.Get(header)
+		if !validHeaderValue(subject) {
+			return auth.Principal{}, apierror.Wrap(apierror.ErrUnauthorized, http.StatusUnauthorized,
+				fmt.Sprintf("missing or invalid %s header", header))
+		}
+
+		return auth.Principal{
+			Subject: subject,
+
+			Method: auth.MethodHeader,
+
+			Scopes: []string{auth.ScopeRead, auth.ScopeWrite},
+		}, nil
+	}, "", scope, logger)
+}
+
+// requirePrincipal resolves the principal of each request and checks that it has scope.
+// challenge, if set, is sent in the WWW-Authenticate header of 401 responses.
+func requirePrincipal(
+	resolve func(*http.Request) (auth.Principal, error), challenge, scope string, logger *slog.Logger,
+) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := resolve(r)
+			if err != nil {
+				logging.FromContext(r.Context(), logger).InfoContext(r.Context(), "authentication failed", "error", err)
+
+				if challenge != "" {
+					w.Header().Set("WWW-Authenticate", challenge)
+				}
+				writeError(w, r, logger, err)
+
+				return
+			}
+
+			requestLogger := logging.FromContext(r.Context(), logger).With("subject", principal.Subject)
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.EnduserID(principal.Subject))
+
+			if !principal.HasScope(scope) {
+				requestLogger.InfoContext(r.Context(), "permission denied", "scope", scope)
+				writeError(w, r, logger, apierror.Wrap(apierror.ErrForbidden, http.StatusForbidden,
+					fmt.Sprintf("missing scope %s", scope)))
+
+				return
+			}
+
+			ctx := auth.NewContext(r.Context(), principal)
+			ctx = logging.NewContext(ctx, requestLogger)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeError writes err as an apierror JSON response carrying the request ID.
+func writeError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	resp := apierror.Response(err, requestid.FromContext(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.ErrorContext(r.Context(), "failed to encode error response", "error", err)
+	}
+}
+
+// CORS adds cross-origin resource sharing headers for allowed origins and answers
+// preflight requests. It does nothing if no origins are allowed.
+func CORS(cfg config.CORSConfig) Middleware {
+	allowMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		if len(cfg.AllowedOrigins) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			if origin == "" || (!anyOrigin && !slices.Contains(cfg.AllowedOrigins, origin)) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				if exposeHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			// Preflight request.
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
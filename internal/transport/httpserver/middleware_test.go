@@ -0,0 +1,498 @@
+package httpserver_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brkcnr/golandworks-api/internal/apierror"
+	"github.com/brkcnr/golandworks-api/internal/auth"
+	"github.com/brkcnr/golandworks-api/internal/config"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+	"github.com/brkcnr/golandworks-api/internal/requestid"
+	"github.com/brkcnr/golandworks-api/internal/transport/httpserver"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// discardLogger returns a logger that drops every record.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	record := func(name string) httpserver.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := httpserver.Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}), record("first"), record("second"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := []string{"first", "second", "handler"}; !slices.Equal(order, want) {
+		t.Errorf("Expected order %v, got %v", want, order)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name        string
+		trustClient bool
+		header      string
+		wantHeader  bool
+	}{
+		{name: "generated", trustClient: true},
+		{name: "accepted from client", trustClient: true, header: "req-1", wantHeader: true},
+		{name: "client ID not trusted", trustClient: false, header: "req-1"},
+		{name: "invalid client ID", trustClient: true, header: "req 1"},
+		{name: "client ID too long", trustClient: true, header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := httpserver.RequestID(tt.trustClient)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				seen = requestid.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			got := w.Header().Get(requestid.Header)
+			if got == "" || got != seen {
+				t.Fatalf("Expected the echoed ID %q to match the context ID %q", got, seen)
+			}
+
+			if (got == tt.header) != tt.wantHeader {
+				t.Errorf("Expected client ID kept = %v, got ID %q", tt.wantHeader, got)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context(), discardLogger()).Info("handled")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	h := httpserver.Chain(mux, httpserver.RequestID(true), httpserver.AccessLog(logger, mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/7", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected a handler record and an access record, got %s", logs.String())
+	}
+
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Failed to decode log record %q: %v", line, err)
+		}
+
+		if record["request_id"] != "req-1" || record["route"] != "GET /todo/{id}" || record["method"] != http.MethodGet {
+			t.Errorf("Expected request attributes on every record, got %v", record)
+		}
+
+		if record["msg"] == "request completed" &&
+			(record["status"] != float64(http.StatusAccepted) || record["size"] != float64(4)) {
+			t.Errorf("Unexpected access record: %v", record)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantError  bool
+	}{
+		{
+			name: "panic before writing",
+			handler: func(http.ResponseWriter, *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  true,
+		},
+		{
+			name: "panic after writing",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("boom")
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "no panic",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := httpserver.Chain(tt.handler, httpserver.RequestID(true), httpserver.Recover(discardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(requestid.Header, "req-1")
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			if !tt.wantError {
+				return
+			}
+
+			var response apierror.APIError
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.Kind != apierror.ErrInternalServer.Kind || response.RequestID != "req-1" {
+				t.Errorf("Unexpected error response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		ExposedHeaders: []string{requestid.Header},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name          string
+		cfg           config.CORSConfig
+		method        string
+		origin        string
+		requestMethod string
+		wantStatus    int
+		wantOrigin    string
+		wantMaxAge    string
+	}{
+		{
+			name:          "preflight from allowed origin",
+			cfg:           cfg,
+			method:        http.MethodOptions,
+			origin:        "https://app.example.com",
+			requestMethod: http.MethodPost,
+			wantStatus:    http.StatusNoContent,
+			wantOrigin:    "https://app.example.com",
+			wantMaxAge:    "600",
+		},
+		{
+			name:          "preflight from other origin",
+			cfg:           cfg,
+			method:        http.MethodOptions,
+			origin:        "https://evil.example.com",
+			requestMethod: http.MethodPost,
+			wantStatus:    http.StatusTeapot,
+		},
+		{
+			name:       "simple request from allowed origin",
+			cfg:        cfg,
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusTeapot,
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "any origin",
+			cfg:        config.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:     http.MethodGet,
+			origin:     "https://other.example.com",
+			wantStatus: http.StatusTeapot,
+			wantOrigin: "*",
+		},
+		{
+			name:       "disabled",
+			cfg:        config.CORSConfig{},
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusTeapot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := httpserver.CORS(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/todo", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected allowed origin %q, got %q", tt.wantOrigin, got)
+			}
+
+			if got := w.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Expected max age %q, got %q", tt.wantMaxAge, got)
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		status      int
+		wantName    string
+		wantError   bool
+	}{
+		{
+			name:        "continues incoming trace",
+			path:        "/todo/1",
+			traceparent: "00-" + traceID + "-" + parentSpanID + "-01",
+			status:      http.StatusOK,
+			wantName:    "GET /todo/{id}",
+		},
+		{
+			name:      "server error",
+			path:      "/todo/1",
+			status:    http.StatusInternalServerError,
+			wantName:  "GET /todo/{id}",
+			wantError: true,
+		},
+		{
+			name:     "unmatched route",
+			path:     "/unknown",
+			status:   http.StatusNotFound,
+			wantName: http.MethodGet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /todo/{id}", func(http.ResponseWriter, *http.Request) {})
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			h := httpserver.Chain(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}), httpserver.Tracing(mux), httpserver.AccessLog(logger, mux))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+
+			before := len(recorder.Ended())
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()[before:]
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+
+			if span.Name() != tt.wantName {
+				t.Errorf("span name = %q, want %q", span.Name(), tt.wantName)
+			}
+
+			if tt.traceparent != "" {
+				if got := span.SpanContext().TraceID().String(); got != traceID {
+					t.Errorf("trace ID = %s, want %s", got, traceID)
+				}
+
+				if got := span.Parent().SpanID().String(); got != parentSpanID {
+					t.Errorf("parent span ID = %s, want %s", got, parentSpanID)
+				}
+			}
+
+			if got := span.Status().Code == codes.Error; got != tt.wantError {
+				t.Errorf("error status = %v, want %v", got, tt.wantError)
+			}
+
+			if want := `"trace_id":"` + span.SpanContext().TraceID().String() + `"`; !strings.Contains(logs.String(), want) {
+				t.Errorf("access log %s does not contain %s", logs.String(), want)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	keyHash := sha256.Sum256([]byte("ci-key"))
+	authenticator, err := auth.New(config.AuthConfig{
+		APIKeys: []config.APIKey{{Name: "ci", Hash: keyHash[:]}},
+
+		JWTSecret: "secret",
+	})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+
+	readOnly, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": auth.ScopeRead,
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		value       string
+		scope       string
+		wantStatus  int
+		wantKind    apierror.Kind
+		wantSubject string
+	}{
+		{
+			name:       "no credentials",
+			scope:      auth.ScopeRead,
+			wantStatus: http.StatusUnauthorized,
+			wantKind:   "unauthorized",
+		},
+		{
+			name:       "invalid API key",
+			header:     auth.APIKeyHeader,
+			value:      "wrong",
+			scope:      auth.ScopeRead,
+			wantStatus: http.StatusUnauthorized,
+			wantKind:   "unauthorized",
+		},
+		{
+			name:        "API key",
+			header:      auth.APIKeyHeader,
+			value:       "ci-key",
+			scope:       auth.ScopeWrite,
+			wantStatus:  http.StatusOK,
+			wantSubject: "api_key:ci",
+		},
+		{
+			name:        "token with scope",
+			header:      "Authorization",
+			value:       "Bearer " + readOnly,
+			scope:       auth.ScopeRead,
+			wantStatus:  http.StatusOK,
+			wantSubject: "jwt:user-1",
+		},
+		{
+			name:       "token without scope",
+			header:     "Authorization",
+			value:      "Bearer " + readOnly,
+			scope:      auth.ScopeWrite,
+			wantStatus: http.StatusForbidden,
+			wantKind:   "forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			h := httpserver.Chain(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				principal, _ := auth.FromContext(r.Context())
+				subject = principal.Subject
+			}), httpserver.RequestID(false), httpserver.Authenticate(authenticator, tt.scope, discardLogger()))
+
+			req := httptest.NewRequest(http.MethodGet, "/todo", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if subject != tt.wantSubject {
+				t.Errorf("Expected principal %q in the handler context, got %q", tt.wantSubject, subject)
+			}
+
+			if tt.wantKind == "" {
+				return
+			}
+
+			var body apierror.APIError
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+
+			if body.Kind != tt.wantKind || body.RequestID == "" {
+				t.Errorf("Expected %s error with a request ID, got %+v", tt.wantKind, body)
+			}
+
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
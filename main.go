@@ -1,36 +1,219 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/brkcnr/golandworks-api/internal/auth"
 	"github.com/brkcnr/golandworks-api/internal/config"
 	"github.com/brkcnr/golandworks-api/internal/db"
+	"github.com/brkcnr/golandworks-api/internal/logging"
+	"github.com/brkcnr/golandworks-api/internal/metrics"
 	"github.com/brkcnr/golandworks-api/internal/service"
+	"github.com/brkcnr/golandworks-api/internal/tracing"
 	"github.com/brkcnr/golandworks-api/internal/transport/httpserver"
 )
 
-// main is the entry point for the application.
+// tracingShutdownTimeout bounds flushing buffered spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
+
+var (
+	// errMigrateUsage is returned when the migrate subcommand is called incorrectly.
+	errMigrateUsage = errors.New("usage: migrate up|down|status")
+
+	// errMigrateStorage is returned when migrations are requested without a database.
+	errMigrateStorage = errors.New("migrations require STORAGE=postgres")
+)
+
+// main is the entry point for the application. It is the only place that exits with a
+// non-zero status, so that the deferred cleanup in run always happens first.
 func main() {
+	if err := run(); err != nil {
+		slog.Error("exiting", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run loads the configuration and runs the migrate subcommand or the server until it is stopped.
+func run() error {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	dbConn, err := db.New(cfg.DB)
+	logger := logging.New(cfg.Log, os.Stdout)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Trace, os.Stdout)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
 	}
-	defer dbConn.Close()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+
+		if shutdownErr := shutdownTracing(ctx); shutdownErr != nil {
+			logger.Error("failed to flush traces", "error", shutdownErr)
+		}
+	}()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = runMigrate(cfg, os.Args[2:]); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+
+		return nil
+	}
+
+	appMetrics := metrics.New()
+
+	serverOpts := []httpserver.Option{
+		httpserver.WithConfig(cfg.HTTP),
+
+		httpserver.WithLogger(logger),
+
+		httpserver.WithMetrics(appMetrics),
+	}
+
+	if cfg.Auth.Enabled() {
+		authenticator, authErr := auth.New(cfg.Auth)
+		if authErr != nil {
+			return fmt.Errorf("failed to set up authentication: %w", authErr)
+		}
+
+		serverOpts = append(serverOpts, httpserver.WithAuth(authenticator))
+	} else if cfg.Auth.IdentityHeader != "" {
+		logger.Warn("identifying callers by an unverified header, it must be set by a trusted proxy",
+			"header", cfg.Auth.IdentityHeader)
+
+		serverOpts = append(serverOpts, httpserver.WithIdentityHeader(cfg.Auth.IdentityHeader))
+	} else {
+		logger.Warn("no API keys or JWT keys configured, authentication is disabled")
+	}
+
+	var store db.Storer
+	if cfg.Storage == config.StorageMemory {
+		logger.Warn("using in-memory storage, data will be lost on exit")
+
+		store = db.NewMemory()
+	} else {
+		dbConn, dbErr := db.New(cfg.DB, db.WithLogger(logger), db.WithQueryTracer(tracing.QueryTracer{}))
+		if dbErr != nil {
+			return fmt.Errorf("failed to initialize database: %w", dbErr)
+		}
+		defer dbConn.Close()
+
+		if cfg.DB.AutoMigrate {
+			if err = migrate(context.Background(), dbConn, []string{"up"}); err != nil {
+				return fmt.Errorf("migration failed: %w", err)
+			}
+		}
+
+		store = dbConn
+		serverOpts = append(serverOpts, httpserver.WithReadinessCheck("database", dbConn.Ping))
+		appMetrics.RegisterPool(dbConn.Stat)
+	}
+
+	// Counting at scrape time bypasses the observer so scrapes do not show up as Storer traffic.
+	appMetrics.RegisterTodoCounts(store.CountItems)
+	store = db.Observe(store, appMetrics.ObserveStore, tracing.ObserveStore)
 
 	todoService := service.New(
-		service.WithDB(dbConn),
+		service.WithDB(store),
+
+		service.WithLogger(logger),
+
+		service.WithStrictSubtasks(cfg.Todo.StrictSubtasks),
+
+		service.WithTrashRetention(cfg.Todo.TrashRetention),
 	)
 
-	server := httpserver.New(todoService)
+	server := httpserver.New(todoService, serverOpts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err = server.Serve(); err != nil {
-		log.Printf("Server error: %v", err)
+	// Restore default signal handling once shutdown starts, so a second signal exits immediately.
+	context.AfterFunc(ctx, stop)
+
+	go todoService.RunTrashPurge(ctx, cfg.Todo.TrashPurgeInterval)
+
+	logger.Info("listening", "addr", cfg.HTTP.Addr)
+
+	if err = server.Serve(ctx); err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
 
-		return
+	logger.Info("server stopped")
+
+	return nil
+}
+
+// runMigrate connects to the database and runs the migrate subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if cfg.Storage != config.StoragePostgres {
+		return errMigrateStorage
+	}
+
+	dbConn, err := db.New(cfg.DB)
+	if err != nil {
+		return err
 	}
+	defer dbConn.Close()
+
+	return migrate(context.Background(), dbConn, args)
+}
+
+// migrate runs the migrate subcommand.
+func migrate(ctx context.Context, dbConn *db.DB, args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := dbConn.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			slog.Info("database schema is up to date")
+		}
+
+	case "down":
+		_, found, err := dbConn.MigrateDown(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			slog.Info("no migrations to roll back")
+		}
+
+	case "status":
+		statuses, err := dbConn.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
 }